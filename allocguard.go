@@ -0,0 +1,30 @@
+package apikit
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+	"runtime"
+)
+
+// Middleware for logging requests that allocate more than threshold bytes (experimental).
+// only a sampleRate fraction (0-1) of requests is measured, since runtime.ReadMemStats briefly
+// stops the world. MemStats is process-wide, so the delta also counts whatever concurrent
+// requests allocated meanwhile: treat it as approximate
+func AllocGuardMiddleware(threshold uint64, sampleRate float64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if rand.Float64() >= sampleRate {
+			next(w, r)
+			return
+		}
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		next(w, r)
+		runtime.ReadMemStats(&after)
+
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > threshold {
+			log.Printf("WARNING: %v [%v] - ~%v bytes allocated during request\n", r.Method, r.URL.String(), allocated)
+		}
+	}
+}
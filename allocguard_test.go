@@ -0,0 +1,29 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var allocSink []byte
+
+func TestAllocGuardMiddleware(t *testing.T) {
+	buf := captureLog(t)
+	hungry := func(w http.ResponseWriter, r *http.Request) {
+		allocSink = make([]byte, 8<<20)
+	}
+
+	AllocGuardMiddleware(1<<20, 1, hungry)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(buf.String(), "bytes allocated during request") {
+		t.Fatalf("large allocation not logged, got %q", buf.String())
+	}
+
+	// never sampled
+	buf.Reset()
+	AllocGuardMiddleware(1<<20, 0, hungry)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("logged with a zero sample rate: %q", buf.String())
+	}
+}
@@ -0,0 +1,36 @@
+package apikit
+
+import (
+	"context"
+	"net/http"
+)
+
+// conventional header for API keys, used by APIKeyAuth when no header is given
+const APIKeyHeader = "X-API-Key"
+
+// Middleware for authenticating machine clients by an API key read from header
+// ("" for APIKeyHeader). validate resolves a key to its
+// subject, which is stored in the request context (see SubjectFromContext). any constant-time
+// comparison is up to validate
+func APIKeyAuth(header string, validate func(key string) (subject string, ok bool), next http.HandlerFunc) http.HandlerFunc {
+	if header == "" {
+		header = APIKeyHeader
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(header)
+		if key == "" {
+			Error(w, "API key not present", http.StatusUnauthorized)
+			return
+		}
+
+		subject, ok := validate(key)
+		if !ok {
+			Error(w, "invalid API key", http.StatusUnauthorized)
+			return
+		}
+
+		annotateLog(r.Context(), "subject", subject)
+		next(w, r.WithContext(context.WithValue(r.Context(), subjectKey, subject)))
+	}
+}
@@ -0,0 +1,58 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	validate := func(key string) (string, bool) {
+		if key == "good" {
+			return "svc-a", true
+		}
+		return "", false
+	}
+
+	var subject string
+	h := APIKeyAuth("X-Service-Key", validate, func(w http.ResponseWriter, r *http.Request) {
+		subject, _ = SubjectFromContext(r.Context())
+	})
+
+	for _, tc := range []struct {
+		name, header, key string
+		want              int
+	}{
+		{"valid", "X-Service-Key", "good", http.StatusOK},
+		{"invalid", "X-Service-Key", "bad", http.StatusUnauthorized},
+		{"missing", "", "", http.StatusUnauthorized},
+		{"default header ignored", APIKeyHeader, "good", http.StatusUnauthorized},
+	} {
+		subject = ""
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.header != "" {
+			r.Header.Set(tc.header, tc.key)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, r)
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+		if tc.want == http.StatusOK && subject != "svc-a" {
+			t.Errorf("%s: subject = %q, want svc-a", tc.name, subject)
+		}
+	}
+}
+
+func TestAPIKeyAuthDefaultHeader(t *testing.T) {
+	h := APIKeyAuth("", func(key string) (string, bool) { return key, true }, okHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(APIKeyHeader, "k")
+	rec := httptest.NewRecorder()
+	h(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+}
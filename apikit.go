@@ -2,12 +2,13 @@ package apikit
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"reflect"
-	"time"
+	"sync/atomic"
 
 	"github.com/gosqueak/jwt"
 )
@@ -15,33 +16,82 @@ import (
 // wraps a http.ResponseWriter but records details from the response
 type loggingResponseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode  int
+	wroteHeader bool
 }
 
 func newLoggingResponseWriter(w http.ResponseWriter) *loggingResponseWriter {
-	return &loggingResponseWriter{w, http.StatusOK}
+	return &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 }
 
-// captures the status code (overloaded)
+// captures the status code (overloaded). only the first call takes effect,
+// later ones are dropped with a warning instead of reaching the underlying writer
 func (l *loggingResponseWriter) WriteHeader(code int) {
+	if l.wroteHeader {
+		log.Printf("WARNING: superfluous WriteHeader(%v) ignored, status already %v\n", code, l.statusCode)
+		return
+	}
+
 	l.statusCode = code
+	l.wroteHeader = true
 	l.ResponseWriter.WriteHeader(code)
 }
 
+// a Write without WriteHeader implies 200 (overloaded)
+func (l *loggingResponseWriter) Write(p []byte) (int, error) {
+	l.wroteHeader = true
+	return l.ResponseWriter.Write(p)
+}
+
 // need to implement Hijack for websockets to work.
 func (l *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return l.ResponseWriter.(http.Hijacker).Hijack()
 }
 
+// lets http.ResponseController reach the underlying writer
+func (l *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
+}
+
+// need to implement Flush for streaming responses to work.
+func (l *loggingResponseWriter) Flush() {
+	if f, ok := l.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 var defaultErrorMessages = map[int]string{
-	http.StatusUnauthorized:        "unauthorized",
-	http.StatusBadRequest:          "bad request",
-	http.StatusInternalServerError: "internal server error",
-	http.StatusMethodNotAllowed:    "method not allowed",
+	http.StatusUnauthorized:         "unauthorized",
+	http.StatusForbidden:            "forbidden",
+	http.StatusNotFound:             "not found",
+	http.StatusBadRequest:           "bad request",
+	http.StatusInternalServerError:  "internal server error",
+	http.StatusMethodNotAllowed:     "method not allowed",
+	http.StatusConflict:             "conflict",
+	http.StatusGone:                 "gone",
+	http.StatusPreconditionFailed:   "precondition failed",
+	http.StatusUnsupportedMediaType: "unsupported media type",
+	http.StatusUnprocessableEntity:  "unprocessable entity",
+	http.StatusTooManyRequests:      "too many requests",
+	http.StatusServiceUnavailable:   "service unavailable",
+	http.StatusGatewayTimeout:       "gateway timeout",
+	http.StatusLoopDetected:         "loop detected",
+}
+
+// when set, Error responds with the JSON envelope written by ErrorJSON
+var jsonErrors atomic.Bool
+
+func SetJSONErrors(enabled bool) {
+	jsonErrors.Store(enabled)
 }
 
 // wrapper to http.Error with default error messages
 func Error(w http.ResponseWriter, msg string, code int) {
+	if jsonErrors.Load() {
+		ErrorJSON(w, msg, code)
+		return
+	}
+
 	if msg == "" {
 		msg = defaultErrorMessages[code]
 	}
@@ -49,59 +99,222 @@ func Error(w http.ResponseWriter, msg string, code int) {
 	http.Error(w, msg, code)
 }
 
+type errorEnvelope struct {
+	Error    string `json:"error"`
+	Code     int    `json:"code"`
+	Reason   string `json:"reason,omitempty"`
+	Resource string `json:"resource,omitempty"`
+}
+
+// like Error but the body is a JSON envelope {"error": msg, "code": code}
+func ErrorJSON(w http.ResponseWriter, msg string, code int) {
+	writeErrorEnvelope(w, errorEnvelope{Error: msg, Code: code})
+}
+
+func writeErrorEnvelope(w http.ResponseWriter, env errorEnvelope) {
+	if env.Error == "" {
+		env.Error = defaultErrorMessages[env.Code]
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	WriteJSON(w, env, env.Code)
+}
+
+func WriteBadRequest(w http.ResponseWriter, msg string) {
+	Error(w, msg, http.StatusBadRequest)
+}
+
+func BadRequestf(w http.ResponseWriter, format string, args ...any) {
+	WriteBadRequest(w, fmt.Sprintf(format, args...))
+}
+
+// responds 403 with a machine readable reason code, e.g. "ip_not_allowed"
+func Forbidden(w http.ResponseWriter, reason string) {
+	if jsonErrors.Load() {
+		writeErrorEnvelope(w, errorEnvelope{Code: http.StatusForbidden, Reason: reason})
+		return
+	}
+
+	Error(w, defaultErrorMessages[http.StatusForbidden]+": "+reason, http.StatusForbidden)
+}
+
+// responds 404 with a message naming the missing resource, e.g. "user not found"
+func NotFound(w http.ResponseWriter, resource string) {
+	msg := defaultErrorMessages[http.StatusNotFound]
+	if resource != "" {
+		msg = resource + " " + msg
+	}
+
+	if jsonErrors.Load() {
+		writeErrorEnvelope(w, errorEnvelope{Error: msg, Code: http.StatusNotFound, Resource: resource})
+		return
+	}
+
+	Error(w, msg, http.StatusNotFound)
+}
+
+// for requests that are well-formed but semantically invalid, use WriteBadRequest for
+// malformed ones
+func UnprocessableEntity(w http.ResponseWriter, msg string) {
+	Error(w, msg, http.StatusUnprocessableEntity)
+}
+
+// e.g. for uniqueness violations on create/update
+func Conflict(w http.ResponseWriter, msg string) {
+	Error(w, msg, http.StatusConflict)
+}
+
 type Middleware func(http.HandlerFunc) http.HandlerFunc
 
 func LogMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		lrw := newLoggingResponseWriter(w)
+		// inner middleware (e.g. auth) record their values in the context's log fields
+		r = r.WithContext(withLogFields(r.Context()))
 		next(lrw, r)
-		log.Printf("%v [%v] - %v\n", r.Method, r.URL.String(), lrw.statusCode)
+
+		fields := formatLogFields(EnrichLog(r.Context()))
+		log.Printf("%v [%v] - %v%v\n", r.Method, r.URL.String(), lrw.statusCode, fields)
+	}
+}
+
+// LogMiddleware that doesn't log requests to the given paths, e.g. health check probes
+func LogMiddlewareExcept(skipPaths ...string) Middleware {
+	skip := make(map[string]bool, len(skipPaths))
+	for _, p := range skipPaths {
+		skip[p] = true
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		logged := LogMiddleware(next)
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			if skip[r.URL.Path] {
+				next(w, r)
+				return
+			}
+			logged(w, r)
+		}
 	}
 }
 
 // Middleware for ensuring a cookie exists with a valid token
 func CookieTokenMiddleware(cookieName string, aud jwt.Audience, next http.HandlerFunc) http.HandlerFunc {
+	return CountedCookieTokenMiddleware(cookieName, aud, "", nil, next)
+}
+
+// outcomes counted by CountedCookieTokenMiddleware
+const (
+	AuthValid   = "valid"
+	AuthExpired = "expired"
+	AuthInvalid = "invalid"
+	AuthMissing = "missing"
+	// the token could not be read for a reason other than the above, answered with 500
+	AuthError = "error"
+)
+
+// receives one increment per authenticated request, e.g. backed by a labeled metrics counter
+type AuthCounter interface {
+	Inc(audience, outcome string)
+}
+
+// like CookieTokenMiddleware but counts each request's outcome under the audience label
+func CountedCookieTokenMiddleware(cookieName string, aud jwt.Audience, label string, counter AuthCounter, next http.HandlerFunc) http.HandlerFunc {
+	count := func(outcome string) {
+		if counter != nil {
+			counter.Inc(label, outcome)
+		}
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		token, err := GetTokenFromCookie(r, cookieName)
 
 		if err == http.ErrNoCookie {
+			count(AuthMissing)
 			Error(w, "JWT cookie not present", http.StatusUnauthorized)
 			return
 		}
 
 		if err == jwt.ErrCannotParse {
+			count(AuthInvalid)
 			Error(w, "could not parse JWT", http.StatusUnauthorized)
 			return
 		}
 
 		if err != nil { // something else bad happened :\
+			count(AuthError)
 			Error(w, "", http.StatusInternalServerError)
 			return
 		}
 
+		// checked before IsValid, which panics on a malformed exp claim
+		if TokenRemaining(token) == 0 {
+			count(AuthExpired)
+			Error(w, "invalid JWT", http.StatusUnauthorized)
+			return
+		}
+
 		if !aud.IsValid(token) {
+			count(AuthInvalid)
 			Error(w, "invalid JWT", http.StatusUnauthorized)
 			return
 		}
 
-		next(w, r)
+		count(AuthValid)
+		annotateLog(r.Context(), "subject", tokenSubject(token))
+		annotateLog(r.Context(), "audience", tokenAudience(token))
+		next(w, r.WithContext(context.WithValue(r.Context(), tokenKey, token)))
 	}
 }
 
+// optional cookie attributes for SetHttpOnlyCookieOpts
+type CookieOpts struct {
+	Path     string
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+	// emits the Partitioned attribute (CHIPS) for third-party contexts. implies Secure
+	Partitioned bool
+}
+
 func SetHttpOnlyCookie(w http.ResponseWriter, name, value string, maxAge int, origin string) {
+	SetHttpOnlyCookieOpts(w, name, value, maxAge, origin, CookieOpts{})
+}
+
+func SetHttpOnlyCookieOpts(w http.ResponseWriter, name, value string, maxAge int, origin string, opts CookieOpts) {
 	// add headers to allows transfer of cookies
 	// credentials: 'include' requires that the Access-Control-Allow-Origin header be set to the exact
 	//  origin (that means * will be rejected),
 	//  and the Access-Control-Allow-Credentials header be set to true.
 	w.Header().Set("Access-Control-Allow-Origin", origin)
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
+	SetVary(w, "Origin")
 
-	http.SetCookie(w, &http.Cookie{
+	cookie := &http.Cookie{
 		Name:     name,
 		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		Secure:   opts.Secure || opts.Partitioned,
+		SameSite: opts.SameSite,
 		HttpOnly: true,
 		MaxAge:   maxAge,
-	})
+	}
+
+	// String returns "" for an invalid cookie, in which case nothing is sent (like http.SetCookie)
+	v := cookie.String()
+	if v == "" {
+		return
+	}
+	// http.Cookie has no Partitioned field, so append the attribute by hand
+	if opts.Partitioned {
+		v += "; Partitioned"
+	}
+
+	w.Header().Add("Set-Cookie", v)
+	if rec, ok := findCookieOriginRecorder(w); ok {
+		rec.recordCookieOrigin(v, origin)
+	}
 }
 
 func GetHttpCookie(r *http.Request, name string) (*http.Cookie, error) {
@@ -146,29 +359,28 @@ func Retry[T any](nTries int, fn any, fnargs ...any) (T, error) {
 		values = append(values, reflect.ValueOf(arg))
 	}
 
-	interval := time.Second
-
-	var returnedT T
-	var returnedError any
+	return RetryWithConfig(DefaultRetryConfig, nTries, func() (T, error) {
+		results := fnValue.Call(values)
 
-	for try := 0; try < nTries; try++ {
-		if try > 0 {
-			fmt.Printf("ERROR: %v retrying....\n", returnedError)
-			time.Sleep(interval)
-			// exponential delay
-			interval *= 2
-		}
+		returnedT, _ := results[0].Interface().(T)
+		returnedError, _ := results[1].Interface().(error)
+		return returnedT, returnedError
+	})
+}
 
-		results := fnValue.Call(values)
+// type-safe alternatives to Retry for the common arities, no reflection needed
+func RetryArgs0[T any](nTries int, fn func() (T, error)) (T, error) {
+	return RetryWithConfig(DefaultRetryConfig, nTries, fn)
+}
 
-		returnedT = results[0].Interface().(T)
-		returnedError = results[1].Interface()
+func RetryArgs1[A, T any](nTries int, fn func(A) (T, error), a A) (T, error) {
+	return RetryArgs0(nTries, func() (T, error) { return fn(a) })
+}
 
-		if returnedError != nil { // error was returned, retry
-			continue
-		}
+func RetryArgs2[A, B, T any](nTries int, fn func(A, B) (T, error), a A, b B) (T, error) {
+	return RetryArgs0(nTries, func() (T, error) { return fn(a, b) })
+}
 
-		return returnedT, nil
-	}
-	return returnedT, returnedError.(error)
+func RetryArgs3[A, B, C, T any](nTries int, fn func(A, B, C) (T, error), a A, b B, c C) (T, error) {
+	return RetryArgs0(nTries, func() (T, error) { return fn(a, b, c) })
 }
@@ -0,0 +1,329 @@
+package apikit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gosqueak/apikit/apitest"
+)
+
+func TestRetryArgs(t *testing.T) {
+	concat := func(parts ...string) string {
+		s := ""
+		for _, p := range parts {
+			s += p
+		}
+		return s
+	}
+
+	if got, err := RetryArgs0(1, func() (string, error) { return "x", nil }); err != nil || got != "x" {
+		t.Fatalf("RetryArgs0 = %q, %v", got, err)
+	}
+	if got, err := RetryArgs1(1, func(a string) (string, error) { return concat(a), nil }, "a"); err != nil || got != "a" {
+		t.Fatalf("RetryArgs1 = %q, %v", got, err)
+	}
+	if got, err := RetryArgs2(1, func(a, b string) (string, error) { return concat(a, b), nil }, "a", "b"); err != nil || got != "ab" {
+		t.Fatalf("RetryArgs2 = %q, %v", got, err)
+	}
+	if got, err := RetryArgs3(1, func(a, b string, c int) (string, error) { return concat(a, b, string(rune('0'+c))), nil }, "a", "b", 1); err != nil || got != "ab1" {
+		t.Fatalf("RetryArgs3 = %q, %v", got, err)
+	}
+}
+
+func TestRetryArgsReturnsLastError(t *testing.T) {
+	errBoom := errors.New("boom")
+	calls := 0
+
+	_, err := RetryArgs1(1, func(n int) (int, error) {
+		calls++
+		return n, errBoom
+	}, 7)
+	if err != errBoom {
+		t.Fatalf("err = %v, want %v", err, errBoom)
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1", calls)
+	}
+}
+
+func TestSetHttpOnlyCookieOptsPartitioned(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SetHttpOnlyCookieOpts(rec, "sid", "abc", 60, "https://app.example", CookieOpts{Path: "/", Partitioned: true})
+
+	set := rec.Header().Get("Set-Cookie")
+	for _, attr := range []string{"sid=abc", "HttpOnly", "Secure", "Partitioned"} {
+		if !strings.Contains(set, attr) {
+			t.Errorf("Set-Cookie %q lacks %s", set, attr)
+		}
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example" {
+		t.Errorf("Access-Control-Allow-Origin = %q", got)
+	}
+}
+
+func TestSetHttpOnlyCookieDefaultNotPartitioned(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SetHttpOnlyCookie(rec, "sid", "abc", 60, "https://app.example")
+
+	if set := rec.Header().Get("Set-Cookie"); strings.Contains(set, "Partitioned") {
+		t.Fatalf("Set-Cookie %q is partitioned by default", set)
+	}
+	if rec.Result().Cookies()[0].Value != "abc" {
+		t.Fatal("cookie not set")
+	}
+}
+
+// enables JSON error mode for the rest of the test
+func jsonErrorsOn(t *testing.T) {
+	t.Helper()
+
+	SetJSONErrors(true)
+	t.Cleanup(func() { SetJSONErrors(false) })
+}
+
+// decodes an ErrorJSON response body
+func decodeErrorEnvelope(t *testing.T, rec *httptest.ResponseRecorder) errorEnvelope {
+	t.Helper()
+
+	var env errorEnvelope
+	if err := json.NewDecoder(rec.Body).Decode(&env); err != nil {
+		t.Fatalf("body is not a JSON error envelope: %v", err)
+	}
+	return env
+}
+
+func TestBadRequestf(t *testing.T) {
+	rec := httptest.NewRecorder()
+	BadRequestf(rec, "invalid %s: %q", "limit", "abc")
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", rec.Code)
+	}
+	if got := strings.TrimSpace(rec.Body.String()); got != `invalid limit: "abc"` {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestWriteBadRequestJSON(t *testing.T) {
+	jsonErrorsOn(t)
+
+	rec := httptest.NewRecorder()
+	WriteBadRequest(rec, "")
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", ct)
+	}
+	env := decodeErrorEnvelope(t, rec)
+	if env.Code != http.StatusBadRequest || env.Error != defaultErrorMessages[http.StatusBadRequest] {
+		t.Fatalf("envelope = %+v", env)
+	}
+}
+
+func TestConflict(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Conflict(rec, "")
+	if rec.Code != http.StatusConflict || strings.TrimSpace(rec.Body.String()) != defaultErrorMessages[http.StatusConflict] {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+
+	jsonErrorsOn(t)
+	rec = httptest.NewRecorder()
+	Conflict(rec, "version mismatch")
+	if env := decodeErrorEnvelope(t, rec); env.Code != http.StatusConflict || env.Error != "version mismatch" {
+		t.Fatalf("envelope = %+v", env)
+	}
+}
+
+// counts WriteHeader calls that reach it
+type countingWriter struct {
+	*httptest.ResponseRecorder
+	writeHeaders int
+}
+
+func (c *countingWriter) WriteHeader(code int) {
+	c.writeHeaders++
+	c.ResponseRecorder.WriteHeader(code)
+}
+
+func TestLoggingResponseWriterIgnoresSecondWriteHeader(t *testing.T) {
+	buf := captureLog(t)
+
+	cw := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+	lrw := newLoggingResponseWriter(cw)
+	lrw.WriteHeader(http.StatusCreated)
+	lrw.WriteHeader(http.StatusInternalServerError)
+
+	if lrw.statusCode != http.StatusCreated || cw.Code != http.StatusCreated {
+		t.Fatalf("status = %d (recorded %d), want 201", lrw.statusCode, cw.Code)
+	}
+	if cw.writeHeaders != 1 {
+		t.Fatalf("underlying WriteHeader called %d times, want 1", cw.writeHeaders)
+	}
+	if !strings.Contains(buf.String(), "superfluous WriteHeader(500)") {
+		t.Fatalf("no warning logged, got %q", buf.String())
+	}
+}
+
+func TestLoggingResponseWriterWriteImpliesHeader(t *testing.T) {
+	buf := captureLog(t)
+
+	lrw := newLoggingResponseWriter(httptest.NewRecorder())
+	lrw.Write([]byte("x"))
+	lrw.WriteHeader(http.StatusBadRequest)
+
+	if lrw.statusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", lrw.statusCode)
+	}
+	if !strings.Contains(buf.String(), "superfluous") {
+		t.Fatal("no warning for WriteHeader after Write")
+	}
+}
+
+func TestLogMiddlewareExcept(t *testing.T) {
+	buf := captureLog(t)
+	h := LogMiddlewareExcept("/healthz", "/readyz")(okHandler)
+
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("health checks were logged: %q", buf.String())
+	}
+
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	if !strings.Contains(buf.String(), "GET [/users] - 200") {
+		t.Fatalf("regular request not logged, got %q", buf.String())
+	}
+}
+
+func TestForbidden(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Forbidden(rec, "ip_not_allowed")
+	if rec.Code != http.StatusForbidden || strings.TrimSpace(rec.Body.String()) != "forbidden: ip_not_allowed" {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+
+	jsonErrorsOn(t)
+	rec = httptest.NewRecorder()
+	Forbidden(rec, "ip_not_allowed")
+	env := decodeErrorEnvelope(t, rec)
+	if env.Code != http.StatusForbidden || env.Reason != "ip_not_allowed" || env.Error != "forbidden" {
+		t.Fatalf("envelope = %+v", env)
+	}
+}
+
+func TestCookieTokenMiddleware(t *testing.T) {
+	kit := tokenKit()
+	other := apitest.NewTokenKit("other")
+
+	var subject string
+	h := CookieTokenMiddleware("jwt", kit.Audience, func(w http.ResponseWriter, r *http.Request) {
+		subject, _ = SubjectFromContext(r.Context())
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(kit.TokenCookie("jwt", kit.MintToken("ann", time.Hour)))
+	rec := httptest.NewRecorder()
+	h(rec, r)
+	if rec.Code != http.StatusOK || subject != "ann" {
+		t.Fatalf("valid token: status = %d, subject = %q", rec.Code, subject)
+	}
+
+	for name, cookie := range map[string]*http.Cookie{
+		"expired":   kit.TokenCookie("jwt", kit.MintExpiredToken("ann")),
+		"other aud": other.TokenCookie("jwt", other.MintToken("ann", time.Hour)),
+		"garbage":   {Name: "jwt", Value: "not-a-jwt"},
+		"missing":   nil,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: status = %d, want 401", name, rec.Code)
+		}
+	}
+}
+
+func TestUnprocessableEntity(t *testing.T) {
+	jsonErrorsOn(t)
+
+	rec := httptest.NewRecorder()
+	UnprocessableEntity(rec, "name: must be at most 5 characters")
+	env := decodeErrorEnvelope(t, rec)
+	if rec.Code != http.StatusUnprocessableEntity || env.Code != http.StatusUnprocessableEntity || env.Error != "name: must be at most 5 characters" {
+		t.Fatalf("got %d %+v", rec.Code, env)
+	}
+}
+
+// counts increments per "audience/outcome"
+type fakeAuthCounter map[string]int
+
+func (c fakeAuthCounter) Inc(audience, outcome string) {
+	c[audience+"/"+outcome]++
+}
+
+func TestCountedCookieTokenMiddleware(t *testing.T) {
+	kit := tokenKit()
+	// same audience, different key
+	other := apitest.NewTokenKit("test-aud")
+	counter := fakeAuthCounter{}
+	h := CountedCookieTokenMiddleware("jwt", kit.Audience, "orders", counter, okHandler)
+
+	malformedExp := kit.MintToken("ann", time.Hour)
+	malformedExp.Body.Expiration = "soon"
+
+	for _, cookie := range []*http.Cookie{
+		kit.TokenCookie("jwt", kit.MintToken("ann", time.Hour)),
+		kit.TokenCookie("jwt", kit.MintToken("bob", time.Hour)),
+		kit.TokenCookie("jwt", kit.MintExpiredToken("ann")),
+		other.TokenCookie("jwt", other.MintToken("ann", time.Hour)),
+		kit.TokenCookie("jwt", malformedExp),
+		{Name: "jwt", Value: "garbage"},
+		nil,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		h(httptest.NewRecorder(), r)
+	}
+
+	want := fakeAuthCounter{
+		"orders/" + AuthValid:   2,
+		"orders/" + AuthExpired: 2,
+		"orders/" + AuthInvalid: 2,
+		"orders/" + AuthMissing: 1,
+	}
+	if !reflect.DeepEqual(counter, want) {
+		t.Fatalf("counts = %v, want %v", counter, want)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NotFound(rec, "user")
+	if rec.Code != http.StatusNotFound || strings.TrimSpace(rec.Body.String()) != "user not found" {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	NotFound(rec, "")
+	if strings.TrimSpace(rec.Body.String()) != "not found" {
+		t.Fatalf("no resource: body = %q", rec.Body.String())
+	}
+
+	jsonErrorsOn(t)
+	rec = httptest.NewRecorder()
+	NotFound(rec, "user")
+	if env := decodeErrorEnvelope(t, rec); env.Resource != "user" || env.Error != "user not found" || env.Code != http.StatusNotFound {
+		t.Fatalf("envelope = %+v", env)
+	}
+}
@@ -0,0 +1,38 @@
+// helpers for testing services built on apikit
+package apitest
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+)
+
+// an http.Client that keeps cookies between requests, like a browser, so multi-step
+// flows (login, refresh, access) can be exercised without copying cookies by hand
+func NewSessionClient() *http.Client {
+	// only fails for a non-nil Options with a bad PublicSuffixList
+	jar, _ := cookiejar.New(nil)
+	return &http.Client{Jar: jar}
+}
+
+// the values of the named cookies the client would send to rawURL, for those that are set
+func SessionCookies(client *http.Client, rawURL string, names ...string) map[string]string {
+	set := make(map[string]string)
+	if client.Jar == nil {
+		return set
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return set
+	}
+
+	for _, cookie := range client.Jar.Cookies(u) {
+		for _, name := range names {
+			if cookie.Name == name {
+				set[name] = cookie.Value
+			}
+		}
+	}
+	return set
+}
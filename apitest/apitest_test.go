@@ -0,0 +1,56 @@
+package apitest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionClientKeepsCookies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "scoped", Value: "xyz", Path: "/admin"})
+		case "/me":
+			c, err := r.Cookie("session")
+			if err != nil {
+				http.Error(w, "", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(c.Value))
+		}
+	}))
+	defer srv.Close()
+
+	client := NewSessionClient()
+	for _, path := range []string{"/login", "/me"} {
+		resp, err := client.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%v: status = %d, want 200", path, resp.StatusCode)
+		}
+	}
+
+	got := SessionCookies(client, srv.URL+"/", "session", "scoped", "missing")
+	if len(got) != 1 || got["session"] != "abc" {
+		t.Fatalf("cookies for / = %v, want only session=abc", got)
+	}
+
+	got = SessionCookies(client, srv.URL+"/admin", "scoped")
+	if got["scoped"] != "xyz" {
+		t.Fatalf("cookies for /admin = %v, want scoped=xyz", got)
+	}
+}
+
+func TestSessionCookiesWithoutJar(t *testing.T) {
+	if got := SessionCookies(&http.Client{}, "http://example.com/", "session"); len(got) != 0 {
+		t.Fatalf("cookies without a jar = %v, want none", got)
+	}
+	if got := SessionCookies(NewSessionClient(), "://bad", "session"); len(got) != 0 {
+		t.Fatalf("cookies for a bad URL = %v, want none", got)
+	}
+}
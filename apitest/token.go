@@ -0,0 +1,54 @@
+package apitest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"time"
+
+	"github.com/gosqueak/jwt"
+)
+
+// a throwaway key pair with an issuer and audience, for minting tokens that pass (or, for
+// expiry paths, fail) validation by an apikit token middleware without reaching into jwt
+type TokenKit struct {
+	// pass to CookieTokenMiddleware and friends
+	Audience jwt.Audience
+	issuer   jwt.Issuer
+	audName  string
+}
+
+// generates a fresh key pair for the audience named aud. panics if key generation fails
+func NewTokenKit(aud string) *TokenKit {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic("apitest.NewTokenKit: " + err.Error())
+	}
+
+	return &TokenKit{
+		Audience: jwt.NewAudience(&key.PublicKey, aud),
+		issuer:   jwt.NewIssuer(key, "apitest"),
+		audName:  aud,
+	}
+}
+
+// mints the claims of a token for subject, valid for ttl. sign it with TokenString
+// or TokenCookie
+func (k *TokenKit) MintToken(subject string, ttl time.Duration) jwt.Jwt {
+	return k.issuer.MintToken(subject, k.audName, ttl)
+}
+
+// mints the claims of a token for subject that expired a minute ago
+func (k *TokenKit) MintExpiredToken(subject string) jwt.Jwt {
+	return k.issuer.MintToken(subject, k.audName, -time.Minute)
+}
+
+// the signed token string, as a client would send it
+func (k *TokenKit) TokenString(token jwt.Jwt) string {
+	return k.issuer.StringifyJwt(token)
+}
+
+// the cookie CookieTokenMiddleware reads, carrying the signed token under name
+func (k *TokenKit) TokenCookie(name string, token jwt.Jwt) *http.Cookie {
+	return &http.Cookie{Name: name, Value: k.TokenString(token), Path: "/", HttpOnly: true}
+}
@@ -0,0 +1,44 @@
+package apitest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gosqueak/jwt"
+)
+
+// parses a signed token string back into a jwt.Jwt, as the middleware would
+func parse(t *testing.T, s string) jwt.Jwt {
+	t.Helper()
+	token, err := jwt.FromString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestTokenKit(t *testing.T) {
+	kit := NewTokenKit("orders")
+
+	token := parse(t, kit.TokenString(kit.MintToken("ann", time.Hour)))
+	if !kit.Audience.IsValid(token) {
+		t.Fatal("minted token not valid for its audience")
+	}
+	if token.Body.Subject != "ann" || token.Body.Audience != "orders" {
+		t.Fatalf("claims = %+v", token.Body)
+	}
+	if kit.Audience.IsValid(parse(t, kit.TokenString(kit.MintExpiredToken("ann")))) {
+		t.Fatal("expired token reported valid")
+	}
+	if NewTokenKit("orders").Audience.IsValid(token) {
+		t.Fatal("token valid under another kit's key")
+	}
+
+	c := kit.TokenCookie("jwt", kit.MintToken("ann", time.Hour))
+	if c.Name != "jwt" || c.Path != "/" || !c.HttpOnly {
+		t.Fatalf("TokenCookie = %+v", c)
+	}
+	if !kit.Audience.IsValid(parse(t, c.Value)) {
+		t.Fatal("cookie does not carry a valid signed token")
+	}
+}
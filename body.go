@@ -0,0 +1,100 @@
+package apikit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+)
+
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// drains the request body and replaces it so it can be read again downstream.
+// maxBytes <= 0 means no limit. when the body exceeds maxBytes, ErrBodyTooLarge is returned
+// and the body is still left intact
+func readBody(r *http.Request, maxBytes int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+
+	var reader io.Reader = r.Body
+	if maxBytes > 0 {
+		reader = io.LimitReader(r.Body, maxBytes+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxBytes > 0 && int64(len(body)) > maxBytes {
+		r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		return nil, ErrBodyTooLarge
+	}
+
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// responds 413 for ErrBodyTooLarge and 400 for any other body read failure
+func writeReadBodyError(w http.ResponseWriter, err error) {
+	if err == ErrBodyTooLarge {
+		Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	Error(w, "", http.StatusBadRequest)
+}
+
+// pairs a replacement reader with the original body's Close
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// returns the hex encoded SHA-256 of the request body, leaving the body readable
+func HashBody(r *http.Request, maxBytes int64) (string, error) {
+	body, err := readBody(r, maxBytes)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Middleware for rejecting POST, PUT and PATCH requests that have an empty body
+func RequireBody(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+		default:
+			next(w, r)
+			return
+		}
+
+		if r.ContentLength == 0 || r.Body == nil || r.Body == http.NoBody {
+			Error(w, "request body required", http.StatusBadRequest)
+			return
+		}
+
+		// length unknown (e.g. chunked), peek a byte to see whether anything was sent
+		if r.ContentLength < 0 {
+			peek := make([]byte, 1)
+			n, err := io.ReadFull(r.Body, peek)
+			if n == 0 {
+				if err == io.EOF {
+					Error(w, "request body required", http.StatusBadRequest)
+				} else {
+					Error(w, "", http.StatusBadRequest)
+				}
+				return
+			}
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(peek), r.Body), r.Body}
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,97 @@
+package apikit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHashBody(t *testing.T) {
+	r1 := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":1}`))
+	r2 := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":1}`))
+	r3 := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":2}`))
+
+	h1, err := HashBody(r1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, _ := HashBody(r2, 0)
+	h3, _ := HashBody(r3, 0)
+
+	if h1 != h2 {
+		t.Fatalf("equal bodies hashed differently: %s, %s", h1, h2)
+	}
+	if h1 == h3 {
+		t.Fatal("different bodies hashed the same")
+	}
+
+	// the body is still readable downstream
+	if b, _ := io.ReadAll(r1.Body); string(b) != `{"a":1}` {
+		t.Fatalf("body after hashing = %q", b)
+	}
+}
+
+func TestHashBodyTooLarge(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("0123456789"))
+
+	if _, err := HashBody(r, 4); err != ErrBodyTooLarge {
+		t.Fatalf("err = %v, want ErrBodyTooLarge", err)
+	}
+	if b, _ := io.ReadAll(r.Body); string(b) != "0123456789" {
+		t.Fatalf("body after oversize read = %q, want it intact", b)
+	}
+}
+
+// hides the length of a body, as a chunked request would
+type unknownLengthBody struct {
+	io.Reader
+}
+
+func (unknownLengthBody) Close() error { return nil }
+
+func TestRequireBody(t *testing.T) {
+	var seen string
+	h := RequireBody(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+	})
+
+	for _, tc := range []struct {
+		name   string
+		method string
+		body   io.Reader
+		want   int
+	}{
+		{"post with body", http.MethodPost, strings.NewReader("x"), http.StatusOK},
+		{"empty post", http.MethodPost, nil, http.StatusBadRequest},
+		{"empty put", http.MethodPut, strings.NewReader(""), http.StatusBadRequest},
+		{"get without body", http.MethodGet, nil, http.StatusOK},
+	} {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(tc.method, "/", tc.body))
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+
+	// unknown length: empty is rejected, content is peeked and left intact
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r.ContentLength = -1
+	r.Body = unknownLengthBody{strings.NewReader("")}
+	rec := httptest.NewRecorder()
+	h(rec, r)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("chunked empty: status = %d, want 400", rec.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodPatch, "/", nil)
+	r.ContentLength = -1
+	r.Body = unknownLengthBody{strings.NewReader("hello")}
+	seen = ""
+	h(httptest.NewRecorder(), r)
+	if seen != "hello" {
+		t.Errorf("chunked body read by handler = %q, want hello", seen)
+	}
+}
@@ -0,0 +1,54 @@
+package apikit
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// a ResponseWriter that holds the whole response in memory until flushed to a real writer,
+// so a response can be inspected, altered or discarded before the client sees it
+type BufferedResponseWriter struct {
+	header      http.Header
+	body        bytes.Buffer
+	statusCode  int
+	wroteHeader bool
+}
+
+func NewBufferedResponseWriter() *BufferedResponseWriter {
+	return &BufferedResponseWriter{header: make(http.Header), statusCode: http.StatusOK}
+}
+
+func (b *BufferedResponseWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *BufferedResponseWriter) WriteHeader(code int) {
+	if b.wroteHeader {
+		return
+	}
+	b.statusCode = code
+	b.wroteHeader = true
+}
+
+func (b *BufferedResponseWriter) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
+
+func (b *BufferedResponseWriter) StatusCode() int {
+	return b.statusCode
+}
+
+func (b *BufferedResponseWriter) Body() []byte {
+	return b.body.Bytes()
+}
+
+// copies the buffered headers, status and body to w
+func (b *BufferedResponseWriter) FlushTo(w http.ResponseWriter) error {
+	for k, vs := range b.header {
+		w.Header()[k] = vs
+	}
+	w.WriteHeader(b.statusCode)
+	_, err := w.Write(b.body.Bytes())
+	return err
+}
@@ -0,0 +1,45 @@
+package apikit
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// the client's clock as reported by X-Timestamp (Unix seconds) or else Date
+func clientTime(r *http.Request) (time.Time, bool) {
+	if unix, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64); err == nil {
+		return time.Unix(unix, 0), true
+	}
+
+	if date, err := http.ParseTime(r.Header.Get("Date")); err == nil {
+		return date, true
+	}
+	return time.Time{}, false
+}
+
+// Middleware for logging requests whose X-Timestamp or Date header is more than threshold
+// away from server time, and rejecting them with 400 when reject is set. requests without
+// either header pass untouched
+func ClockSkewMiddleware(threshold time.Duration, reject bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client, ok := clientTime(r)
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		skew := time.Since(client)
+		if skew > threshold || skew < -threshold {
+			log.Printf("WARNING: %v [%v] - client clock skewed by %v\n", r.Method, r.URL.String(), skew.Round(time.Second))
+
+			if reject {
+				BadRequestf(w, "client clock skewed by %v", skew.Round(time.Second))
+				return
+			}
+		}
+
+		next(w, r)
+	}
+}
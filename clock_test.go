@@ -0,0 +1,41 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClockSkewMiddleware(t *testing.T) {
+	buf := captureLog(t)
+
+	skewed := httptest.NewRequest(http.MethodGet, "/", nil)
+	skewed.Header.Set("Date", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+
+	rec := httptest.NewRecorder()
+	ClockSkewMiddleware(time.Minute, false, okHandler)(rec, skewed)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("log only: status = %d, want 200", rec.Code)
+	}
+	if !strings.Contains(buf.String(), "client clock skewed") {
+		t.Fatalf("skew not logged, got %q", buf.String())
+	}
+
+	rec = httptest.NewRecorder()
+	ClockSkewMiddleware(time.Minute, true, okHandler)(rec, skewed)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("reject: status = %d, want 400", rec.Code)
+	}
+
+	// a close X-Timestamp takes precedence over a skewed Date
+	skewed.Header.Set(TimestampHeader, strconv.FormatInt(time.Now().Unix(), 10))
+	buf.Reset()
+	rec = httptest.NewRecorder()
+	ClockSkewMiddleware(time.Minute, true, okHandler)(rec, skewed)
+	if rec.Code != http.StatusOK || buf.Len() != 0 {
+		t.Fatalf("in-sync X-Timestamp: status = %d, log %q", rec.Code, buf.String())
+	}
+}
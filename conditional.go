@@ -0,0 +1,42 @@
+package apikit
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// maps to 412 Precondition Failed
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// writes body with a Last-Modified header, or 304 Not Modified when the client's
+// If-Modified-Since is not older than modtime
+func WriteWithLastModified(w http.ResponseWriter, r *http.Request, modtime time.Time, body []byte) {
+	// HTTP dates only have second precision
+	modtime = modtime.Truncate(time.Second)
+	w.Header().Set("Last-Modified", modtime.UTC().Format(http.TimeFormat))
+
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modtime.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Write(body)
+}
+
+// returns ErrPreconditionFailed when the resource was modified after the client's
+// If-Unmodified-Since, e.g. before a conditional DELETE. passes when the header is absent
+func CheckIfUnmodifiedSince(r *http.Request, modtime time.Time) error {
+	since, err := http.ParseTime(r.Header.Get("If-Unmodified-Since"))
+	if err != nil {
+		// absent or unparseable, RFC 9110 says to ignore it
+		return nil
+	}
+
+	if modtime.Truncate(time.Second).After(since) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
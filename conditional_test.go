@@ -0,0 +1,62 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWriteWithLastModified(t *testing.T) {
+	modtime := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+
+	for _, tc := range []struct {
+		name  string
+		since string
+		want  int
+	}{
+		{"no header", "", http.StatusOK},
+		{"not modified", modtime.Format(http.TimeFormat), http.StatusNotModified},
+		{"modified since", modtime.Add(-time.Hour).Format(http.TimeFormat), http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.since != "" {
+			r.Header.Set("If-Modified-Since", tc.since)
+		}
+		rec := httptest.NewRecorder()
+		WriteWithLastModified(rec, r, modtime, []byte("body"))
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+		if got := rec.Header().Get("Last-Modified"); got != modtime.Format(http.TimeFormat) {
+			t.Errorf("%s: Last-Modified = %q", tc.name, got)
+		}
+		if tc.want == http.StatusNotModified && rec.Body.Len() != 0 {
+			t.Errorf("%s: 304 with a body", tc.name)
+		}
+	}
+}
+
+func TestCheckIfUnmodifiedSince(t *testing.T) {
+	modtime := time.Date(2024, 1, 2, 3, 4, 5, 600, time.UTC)
+
+	for _, tc := range []struct {
+		name   string
+		header string
+		want   error
+	}{
+		{"absent", "", nil},
+		{"unparseable", "yesterday", nil},
+		{"unchanged", modtime.Format(http.TimeFormat), nil},
+		{"modified since", modtime.Add(-time.Hour).Format(http.TimeFormat), ErrPreconditionFailed},
+	} {
+		r := httptest.NewRequest(http.MethodDelete, "/", nil)
+		if tc.header != "" {
+			r.Header.Set("If-Unmodified-Since", tc.header)
+		}
+		if err := CheckIfUnmodifiedSince(r, modtime); err != tc.want {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
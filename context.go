@@ -0,0 +1,124 @@
+package apikit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gosqueak/jwt"
+)
+
+// keys for the values apikit stores in request contexts
+type contextKey int
+
+const (
+	sequenceKey contextKey = iota
+	tokenKey
+	subjectKey
+	requestIDKey
+	logFieldsKey
+)
+
+// process-wide request counter
+var requestSequence atomic.Uint64
+
+// Middleware for numbering requests in the order they arrive. must wrap LogMiddleware
+// for the number to show up in the log line
+func SequenceMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		seq := requestSequence.Add(1)
+		next(w, r.WithContext(context.WithValue(r.Context(), sequenceKey, seq)))
+	}
+}
+
+func SequenceFromContext(ctx context.Context) (uint64, bool) {
+	seq, ok := ctx.Value(sequenceKey).(uint64)
+	return seq, ok
+}
+
+// the token validated by CookieTokenMiddleware
+func TokenFromContext(ctx context.Context) (jwt.Jwt, bool) {
+	token, ok := ctx.Value(tokenKey).(jwt.Jwt)
+	return token, ok
+}
+
+// the authenticated subject, from APIKeyAuth or the token validated by CookieTokenMiddleware
+func SubjectFromContext(ctx context.Context) (string, bool) {
+	if subject, ok := ctx.Value(subjectKey).(string); ok {
+		return subject, true
+	}
+
+	if token, ok := TokenFromContext(ctx); ok {
+		return tokenSubject(token), true
+	}
+	return "", false
+}
+
+// filled in by middleware running inside LogMiddleware, which only sees its own context
+type logFields struct {
+	mu     sync.Mutex
+	fields map[string]any
+}
+
+func withLogFields(ctx context.Context) context.Context {
+	return context.WithValue(ctx, logFieldsKey, &logFields{fields: make(map[string]any)})
+}
+
+// records a field for the log line of the request, if LogMiddleware wraps it
+func annotateLog(ctx context.Context, key string, value any) {
+	lf, ok := ctx.Value(logFieldsKey).(*logFields)
+	if !ok {
+		return
+	}
+
+	lf.mu.Lock()
+	defer lf.mu.Unlock()
+	lf.fields[key] = value
+}
+
+// gathers the apikit managed values present in ctx (request ID, sequence, subject, audience)
+// for structured logging, including those recorded by middleware inside LogMiddleware
+func EnrichLog(ctx context.Context) map[string]any {
+	fields := make(map[string]any)
+
+	if lf, ok := ctx.Value(logFieldsKey).(*logFields); ok {
+		lf.mu.Lock()
+		for k, v := range lf.fields {
+			fields[k] = v
+		}
+		lf.mu.Unlock()
+	}
+
+	if id, ok := RequestIDFromContext(ctx); ok {
+		fields["request_id"] = id
+	}
+	if seq, ok := SequenceFromContext(ctx); ok {
+		fields["seq"] = seq
+	}
+	if subject, ok := SubjectFromContext(ctx); ok {
+		fields["subject"] = subject
+	}
+	if token, ok := TokenFromContext(ctx); ok {
+		fields["audience"] = tokenAudience(token)
+	}
+	return fields
+}
+
+// renders fields as " key=value" pairs in key order, "" when empty
+func formatLogFields(fields map[string]any) string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %v=%v", k, fields[k])
+	}
+	return b.String()
+}
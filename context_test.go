@@ -0,0 +1,109 @@
+package apikit
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSequenceMiddlewareConcurrent(t *testing.T) {
+	const n = 100
+
+	var mu sync.Mutex
+	seen := make(map[uint64]bool)
+
+	h := SequenceMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		seq, ok := SequenceFromContext(r.Context())
+		if !ok {
+			t.Error("no sequence number in context")
+			return
+		}
+		mu.Lock()
+		seen[seq] = true
+		mu.Unlock()
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != n {
+		t.Fatalf("got %d distinct sequence numbers, want %d", len(seen), n)
+	}
+}
+
+func TestSequenceInLogLine(t *testing.T) {
+	buf := captureLog(t)
+
+	SequenceMiddleware(LogMiddleware(okHandler))(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !strings.Contains(buf.String(), " seq=") {
+		t.Fatalf("log line %q has no seq field", buf.String())
+	}
+}
+
+// redirects the standard logger into the returned buffer for the rest of the test
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	prev := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(prev) })
+	return &buf
+}
+
+func TestLogLineCarriesInnerAuthFields(t *testing.T) {
+	buf := captureLog(t)
+	kit := tokenKit()
+
+	h := RequestIDMiddleware(LogMiddleware(CookieTokenMiddleware("jwt", kit.Audience, okHandler)))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "req-7")
+	r.AddCookie(kit.TokenCookie("jwt", kit.MintToken("ann", time.Hour)))
+	h(httptest.NewRecorder(), r)
+
+	for _, field := range []string{"request_id=req-7", "subject=ann", "audience=test-aud"} {
+		if !strings.Contains(buf.String(), field) {
+			t.Errorf("log line %q lacks %s", buf.String(), field)
+		}
+	}
+}
+
+func TestLogLineCarriesAPIKeySubject(t *testing.T) {
+	buf := captureLog(t)
+
+	h := LogMiddleware(APIKeyAuth("", func(key string) (string, bool) { return "svc-" + key, true }, okHandler))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(APIKeyHeader, "a")
+	h(httptest.NewRecorder(), r)
+
+	if !strings.Contains(buf.String(), "subject=svc-a") {
+		t.Fatalf("log line %q lacks the subject", buf.String())
+	}
+}
+
+func TestEnrichLogWithoutLogMiddleware(t *testing.T) {
+	// annotating without a holder in the context is a no-op
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	annotateLog(r.Context(), "subject", "ann")
+
+	if fields := EnrichLog(r.Context()); len(fields) != 0 {
+		t.Fatalf("EnrichLog = %v, want no fields", fields)
+	}
+	if got := formatLogFields(map[string]any{"b": 2, "a": 1}); got != " a=1 b=2" {
+		t.Fatalf("formatLogFields = %q", got)
+	}
+}
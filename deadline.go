@@ -0,0 +1,42 @@
+package apikit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// carries a request's deadline between services, as Unix milliseconds
+const DeadlineHeader = "X-Deadline"
+
+// sets X-Deadline on an outgoing request from ctx's deadline, if it has one
+func PropagateDeadline(ctx context.Context, out *http.Request) {
+	if deadline, ok := ctx.Deadline(); ok {
+		out.Header.Set(DeadlineHeader, strconv.FormatInt(deadline.UnixMilli(), 10))
+	}
+}
+
+func DeadlineFromHeader(h http.Header) (time.Time, bool) {
+	millis, err := strconv.ParseInt(h.Get(DeadlineHeader), 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(millis), true
+}
+
+// Middleware for adopting the deadline sent by an upstream service in X-Deadline
+func DeadlineMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deadline, ok := DeadlineFromHeader(r.Header)
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+
+		next(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,42 @@
+package apikit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeadlineRoundTrip(t *testing.T) {
+	deadline := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	out := httptest.NewRequest(http.MethodGet, "/", nil)
+	PropagateDeadline(ctx, out)
+
+	var got time.Time
+	var ok bool
+	DeadlineMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		got, ok = r.Context().Deadline()
+	})(httptest.NewRecorder(), out)
+
+	if !ok || !got.Equal(deadline) {
+		t.Fatalf("deadline = %v, %v; want %v", got, ok, deadline)
+	}
+}
+
+func TestDeadlineMiddlewareWithoutHeader(t *testing.T) {
+	DeadlineMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Deadline(); ok {
+			t.Error("deadline set without a header")
+		}
+	})(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	out := httptest.NewRequest(http.MethodGet, "/", nil)
+	PropagateDeadline(context.Background(), out)
+	if out.Header.Get(DeadlineHeader) != "" {
+		t.Error("header set for a context without a deadline")
+	}
+}
@@ -0,0 +1,24 @@
+package apikit
+
+import (
+	"net/http"
+	"time"
+)
+
+// Middleware for marking an endpoint deprecated, announcing its removal date in the Sunset
+// header. with hardCutoff the endpoint answers 410 Gone once sunset has passed, so removal
+// can be scheduled without a redeploy
+func Deprecated(sunset time.Time, hardCutoff bool, next http.HandlerFunc) http.HandlerFunc {
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if hardCutoff && !time.Now().Before(sunset) {
+			Error(w, "", http.StatusGone)
+			return
+		}
+
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Sunset", sunsetHeader)
+		next(w, r)
+	}
+}
@@ -0,0 +1,46 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeprecatedBeforeSunset(t *testing.T) {
+	sunset := time.Now().Add(24 * time.Hour)
+	for _, hardCutoff := range []bool{false, true} {
+		rec := httptest.NewRecorder()
+		Deprecated(sunset, hardCutoff, okHandler)(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("hardCutoff=%v: status = %d, want 200", hardCutoff, rec.Code)
+		}
+		if got := rec.Header().Get("Deprecation"); got != "true" {
+			t.Fatalf("Deprecation = %q, want true", got)
+		}
+		if got, want := rec.Header().Get("Sunset"), sunset.UTC().Format(http.TimeFormat); got != want {
+			t.Fatalf("Sunset = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestDeprecatedAfterSunset(t *testing.T) {
+	sunset := time.Now().Add(-time.Hour)
+
+	rec := httptest.NewRecorder()
+	Deprecated(sunset, false, okHandler)(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Sunset") == "" {
+		t.Fatalf("soft deprecation past sunset: status = %d, Sunset = %q, want 200 with the header", rec.Code, rec.Header().Get("Sunset"))
+	}
+
+	called := false
+	rec = httptest.NewRecorder()
+	Deprecated(sunset, true, func(w http.ResponseWriter, r *http.Request) { called = true })(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusGone {
+		t.Fatalf("hard cutoff past sunset: status = %d, want 410", rec.Code)
+	}
+	if called {
+		t.Fatal("handler ran after the hard cutoff")
+	}
+}
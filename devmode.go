@@ -0,0 +1,97 @@
+package apikit
+
+import (
+	"log"
+	"net/http"
+	"sync/atomic"
+)
+
+var devMode atomic.Bool
+
+// enables development-only checks such as middleware ordering warnings. meant for
+// local and staging environments
+func SetDevMode(enabled bool) {
+	devMode.Store(enabled)
+}
+
+func DevMode() bool {
+	return devMode.Load()
+}
+
+// in dev mode, loudly warns when middleware ran without the prerequisite that must wrap it
+func assertPrerequisite(present bool, middleware, prerequisite string) {
+	if present || !DevMode() {
+		return
+	}
+	log.Printf("WARNING: %v ran without %v, check the middleware order\n", middleware, prerequisite)
+}
+
+// Middleware for catching handlers that write a body without a Content-Type. in dev mode
+// a warning is logged on the first Write and application/octet-stream set so clients
+// don't sniff. the response otherwise passes straight through, so streaming still works.
+// outside dev mode it does nothing
+func ContentTypeCheckMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !DevMode() {
+			next(w, r)
+			return
+		}
+
+		cw := &contentTypeCheckWriter{ResponseWriter: w, r: r}
+		next(cw, r)
+		cw.sendHeader(nil)
+	}
+}
+
+// holds back the status until the first Write or Flush, so Content-Type can still be set
+type contentTypeCheckWriter struct {
+	http.ResponseWriter
+	r          *http.Request
+	code       int
+	headerSent bool
+}
+
+// checks the Content-Type and sends the held back status. p is the first chunk of the body,
+// nil if there is none yet
+func (cw *contentTypeCheckWriter) sendHeader(p []byte) {
+	if cw.headerSent {
+		return
+	}
+
+	if len(p) > 0 && cw.Header().Get("Content-Type") == "" {
+		log.Printf("WARNING: %v [%v] - response body written without a Content-Type\n", cw.r.Method, cw.r.URL.String())
+		cw.Header().Set("Content-Type", "application/octet-stream")
+	}
+
+	if cw.code == 0 && p == nil {
+		// nothing written yet, leave the status to the next writer
+		return
+	}
+	cw.headerSent = true
+	if cw.code != 0 {
+		cw.ResponseWriter.WriteHeader(cw.code)
+	}
+}
+
+func (cw *contentTypeCheckWriter) WriteHeader(code int) {
+	if cw.headerSent || cw.code != 0 {
+		return
+	}
+	cw.code = code
+}
+
+func (cw *contentTypeCheckWriter) Write(p []byte) (int, error) {
+	cw.sendHeader(p)
+	return cw.ResponseWriter.Write(p)
+}
+
+func (cw *contentTypeCheckWriter) Flush() {
+	cw.sendHeader(nil)
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *contentTypeCheckWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
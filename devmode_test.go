@@ -0,0 +1,105 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// enables dev mode for the rest of the test
+func devModeOn(t *testing.T) {
+	t.Helper()
+
+	SetDevMode(true)
+	t.Cleanup(func() { SetDevMode(false) })
+}
+
+func TestQuotaBySubjectWithoutAuthWarnsInDevMode(t *testing.T) {
+	buf := captureLog(t)
+	h := QuotaMiddleware(NewMemoryQuotaStore(1, time.Hour), QuotaKeyFromTokenSubject(), okHandler)
+
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("warned outside dev mode: %q", buf.String())
+	}
+
+	devModeOn(t)
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(buf.String(), "ran without CookieTokenMiddleware") {
+		t.Fatalf("no ordering warning in dev mode, got %q", buf.String())
+	}
+}
+
+func TestContentTypeCheckMiddleware(t *testing.T) {
+	buf := captureLog(t)
+	h := ContentTypeCheckMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>"))
+	})
+
+	// outside dev mode the server's sniffing is left alone
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if buf.Len() != 0 || rec.Body.String() != "<html>" {
+		t.Fatalf("outside dev mode: log %q, body %q", buf.String(), rec.Body.String())
+	}
+
+	devModeOn(t)
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(buf.String(), "without a Content-Type") {
+		t.Fatalf("no warning in dev mode, got %q", buf.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/octet-stream" {
+		t.Fatalf("Content-Type = %q, want application/octet-stream", got)
+	}
+
+	buf.Reset()
+	ContentTypeCheckMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, 1, http.StatusOK)
+	})(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("warned about a typed response: %q", buf.String())
+	}
+}
+
+func TestContentTypeCheckMiddlewarePassesThrough(t *testing.T) {
+	devModeOn(t)
+	buf := captureLog(t)
+
+	// the held back status still goes out with the Content-Type set in time
+	rec := httptest.NewRecorder()
+	ContentTypeCheckMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("raw"))
+	})(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	if rec.Code != http.StatusCreated || rec.Header().Get("Content-Type") != "application/octet-stream" || rec.Body.String() != "raw" {
+		t.Fatalf("got %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	ContentTypeCheckMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})(rec, httptest.NewRequest(http.MethodDelete, "/", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status-only response: got %d, want 204", rec.Code)
+	}
+
+	// streaming writers still find the Flusher
+	buf.Reset()
+	rec = httptest.NewRecorder()
+	ContentTypeCheckMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		sse, err := NewSSEWriter(w, r)
+		if err != nil {
+			t.Fatalf("NewSSEWriter in dev mode: %v", err)
+		}
+		sse.Send("tick", []byte("a"))
+	})(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if !rec.Flushed || !strings.Contains(rec.Body.String(), "data: a") {
+		t.Fatalf("stream not flushed through: flushed=%v body %q", rec.Flushed, rec.Body.String())
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("warned about a typed stream: %q", buf.String())
+	}
+}
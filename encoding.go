@@ -0,0 +1,52 @@
+package apikit
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Middleware for rejecting request bodies whose Content-Encoding isn't in allowed with 415.
+// unencoded (identity) bodies are always accepted
+func AllowContentEncodings(allowed []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, value := range r.Header.Values("Content-Encoding") {
+			for _, encoding := range strings.Split(value, ",") {
+				encoding = strings.ToLower(strings.TrimSpace(encoding))
+				if encoding == "" || encoding == "identity" || contains(allowed, encoding) {
+					continue
+				}
+
+				Error(w, "content encoding "+encoding+" not allowed", http.StatusUnsupportedMediaType)
+				return
+			}
+		}
+
+		next(w, r)
+	}
+}
+
+// Middleware for rejecting bodies whose Content-Type declares a charset other than UTF-8
+// with 415. bodies without a charset parameter pass
+func RequireUTF8(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		if contentType == "" {
+			next(w, r)
+			return
+		}
+
+		_, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			Error(w, "invalid Content-Type", http.StatusBadRequest)
+			return
+		}
+
+		if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") && !strings.EqualFold(charset, "utf8") {
+			Error(w, "charset "+charset+" not supported, use utf-8", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		next(w, r)
+	}
+}
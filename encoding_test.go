@@ -0,0 +1,58 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowContentEncodings(t *testing.T) {
+	h := AllowContentEncodings([]string{"gzip"}, okHandler)
+
+	for _, tc := range []struct {
+		encoding string
+		want     int
+	}{
+		{"", http.StatusOK},
+		{"identity", http.StatusOK},
+		{"GZIP", http.StatusOK},
+		{"br", http.StatusUnsupportedMediaType},
+		{"gzip, br", http.StatusUnsupportedMediaType},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tc.encoding != "" {
+			r.Header.Set("Content-Encoding", tc.encoding)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != tc.want {
+			t.Errorf("%q: status = %d, want %d", tc.encoding, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestRequireUTF8(t *testing.T) {
+	h := RequireUTF8(okHandler)
+
+	for _, tc := range []struct {
+		contentType string
+		want        int
+	}{
+		{"", http.StatusOK},
+		{"application/json", http.StatusOK},
+		{"application/json; charset=UTF-8", http.StatusOK},
+		{"text/plain; charset=utf8", http.StatusOK},
+		{"text/plain; charset=latin1", http.StatusUnsupportedMediaType},
+		{"text/plain; charset", http.StatusBadRequest},
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tc.contentType != "" {
+			r.Header.Set("Content-Type", tc.contentType)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != tc.want {
+			t.Errorf("%q: status = %d, want %d", tc.contentType, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package apikit
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+)
+
+var ErrInvalidCookie = errors.New("cookie could not be decrypted")
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// like SetHttpOnlyCookie but the value is encrypted and authenticated with AES-256-GCM,
+// so the client can neither read nor alter it
+func SetEncryptedCookie(w http.ResponseWriter, name, value string, maxAge int, origin string, key []byte) error {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	// the cookie name is authenticated too, so a value can't be moved to another cookie
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(name))
+	SetHttpOnlyCookie(w, name, base64.RawURLEncoding.EncodeToString(sealed), maxAge, origin)
+	return nil
+}
+
+// decrypts a cookie set by SetEncryptedCookie. returns ErrInvalidCookie if it was
+// tampered with or encrypted under a different key
+func GetEncryptedCookie(r *http.Request, name string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	cookie, err := GetHttpCookie(r, name)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", ErrInvalidCookie
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	value, err := gcm.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	return string(value), nil
+}
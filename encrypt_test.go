@@ -0,0 +1,60 @@
+package apikit
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// copies the cookies set on rec into a new request, as a browser would send them back
+func requestWithCookies(rec *httptest.ResponseRecorder) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+func TestEncryptedCookieRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+
+	rec := httptest.NewRecorder()
+	if err := SetEncryptedCookie(rec, "session", "user=ann", 60, "https://app.example", key); err != nil {
+		t.Fatal(err)
+	}
+
+	if c := rec.Result().Cookies()[0]; bytes.Contains([]byte(c.Value), []byte("ann")) {
+		t.Fatalf("cookie value %q is not encrypted", c.Value)
+	}
+
+	got, err := GetEncryptedCookie(requestWithCookies(rec), "session", key)
+	if err != nil || got != "user=ann" {
+		t.Fatalf("got %q, %v; want user=ann", got, err)
+	}
+}
+
+func TestEncryptedCookieRejectsTampering(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), 32)
+
+	rec := httptest.NewRecorder()
+	SetEncryptedCookie(rec, "session", "user=ann", 60, "https://app.example", key)
+
+	if _, err := GetEncryptedCookie(requestWithCookies(rec), "session", bytes.Repeat([]byte("x"), 32)); err != ErrInvalidCookie {
+		t.Errorf("wrong key: err = %v, want ErrInvalidCookie", err)
+	}
+
+	// the same value moved to a cookie with another name
+	c := rec.Result().Cookies()[0]
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "other", Value: c.Value})
+	if _, err := GetEncryptedCookie(r, "other", key); err != ErrInvalidCookie {
+		t.Errorf("renamed cookie: err = %v, want ErrInvalidCookie", err)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: c.Value[:len(c.Value)-2] + "AA"})
+	if _, err := GetEncryptedCookie(r, "session", key); err != ErrInvalidCookie {
+		t.Errorf("modified value: err = %v, want ErrInvalidCookie", err)
+	}
+}
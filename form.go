@@ -0,0 +1,96 @@
+package apikit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+// parses a form-urlencoded body (and query) into the struct pointed to by dst. fields are
+// matched by their `form:"name"` tag, or their name when untagged. `form:"-"` skips a field.
+// supports strings, bools, ints, uints, floats and slices of those. conversion errors name the field
+func DecodeForm(r *http.Request, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return errors.New("dst must be a pointer to a struct")
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Tag.Get("form")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		values, ok := r.Form[name]
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(fv.Type(), len(values), len(values))
+			for j, s := range values {
+				if err := setFormValue(slice.Index(j), s); err != nil {
+					return fmt.Errorf("field %v: %w", name, err)
+				}
+			}
+			fv.Set(slice)
+			continue
+		}
+
+		if err := setFormValue(fv, values[0]); err != nil {
+			return fmt.Errorf("field %v: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setFormValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %v", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newFormRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestDecodeForm(t *testing.T) {
+	var dst struct {
+		Name    string   `form:"name"`
+		Age     int      `form:"age"`
+		Admin   bool     `form:"admin"`
+		Score   float64  `form:"score"`
+		Tags    []string `form:"tag"`
+		Count   uint8
+		Ignored string `form:"-"`
+		secret  string
+	}
+
+	r := newFormRequest("name=ann&age=41&admin=true&score=9.5&tag=a&tag=b&Count=3&Ignored=x&secret=y")
+	if err := DecodeForm(r, &dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if dst.Name != "ann" || dst.Age != 41 || !dst.Admin || dst.Score != 9.5 || dst.Count != 3 {
+		t.Fatalf("decoded %+v", dst)
+	}
+	if !reflect.DeepEqual(dst.Tags, []string{"a", "b"}) {
+		t.Fatalf("Tags = %v", dst.Tags)
+	}
+	if dst.Ignored != "" || dst.secret != "" {
+		t.Fatal("skipped fields were set")
+	}
+}
+
+func TestDecodeFormErrors(t *testing.T) {
+	var dst struct {
+		Age int `form:"age"`
+	}
+
+	err := DecodeForm(newFormRequest("age=old"), &dst)
+	if err == nil || !strings.Contains(err.Error(), "field age") {
+		t.Fatalf("err = %v, want one naming the field", err)
+	}
+
+	if err := DecodeForm(newFormRequest("age=1"), dst); err == nil {
+		t.Fatal("no error for a non-pointer dst")
+	}
+}
@@ -0,0 +1,66 @@
+package apikit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// runs a request's concurrent downstream calls, cancelling the rest on the first error.
+// modelled on errgroup.Group, but bound to the request context
+type Group struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+// a Group whose tasks run under r's context, so they also stop when the client goes away
+// or the request deadline (e.g. from DeadlineMiddleware) passes
+func NewRequestGroup(r *http.Request) *Group {
+	ctx, cancel := context.WithCancel(r.Context())
+	return &Group{parent: r.Context(), ctx: ctx, cancel: cancel}
+}
+
+// runs fn in a goroutine. ctx is cancelled once any task fails
+func (g *Group) Go(fn func(ctx context.Context) error) {
+	g.wg.Add(1)
+
+	go func() {
+		defer g.wg.Done()
+
+		if err := fn(g.ctx); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// waits for every task and returns the first error
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
+
+// like Wait but on failure writes the response (504 when the request deadline was the cause,
+// 500 otherwise) and returns false
+func (g *Group) WaitOrError(w http.ResponseWriter) bool {
+	err := g.Wait()
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || g.parent.Err() == context.DeadlineExceeded {
+		Error(w, "", http.StatusGatewayTimeout)
+		return false
+	}
+
+	Error(w, "", http.StatusInternalServerError)
+	return false
+}
@@ -0,0 +1,67 @@
+package apikit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGroupCancelsOnFirstError(t *testing.T) {
+	g := NewRequestGroup(httptest.NewRequest(http.MethodGet, "/", nil))
+	errBoom := errors.New("boom")
+
+	cancelled := make(chan bool, 1)
+	g.Go(func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			cancelled <- true
+		case <-time.After(5 * time.Second):
+			cancelled <- false
+		}
+		return nil
+	})
+	g.Go(func(ctx context.Context) error { return errBoom })
+
+	if err := g.Wait(); err != errBoom {
+		t.Fatalf("Wait = %v, want %v", err, errBoom)
+	}
+	if !<-cancelled {
+		t.Fatal("sibling task was not cancelled")
+	}
+}
+
+func TestGroupWaitOrError(t *testing.T) {
+	g := NewRequestGroup(httptest.NewRequest(http.MethodGet, "/", nil))
+	g.Go(func(ctx context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	if !g.WaitOrError(rec) {
+		t.Fatal("WaitOrError = false for successful tasks")
+	}
+
+	g = NewRequestGroup(httptest.NewRequest(http.MethodGet, "/", nil))
+	g.Go(func(ctx context.Context) error { return errors.New("boom") })
+	rec = httptest.NewRecorder()
+	if g.WaitOrError(rec) || rec.Code != http.StatusInternalServerError {
+		t.Fatalf("failed task: status = %d, want 500", rec.Code)
+	}
+}
+
+func TestGroupWaitOrErrorDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	g := NewRequestGroup(httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	g.Go(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	rec := httptest.NewRecorder()
+	if g.WaitOrError(rec) || rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("deadline: status = %d, want 504", rec.Code)
+	}
+}
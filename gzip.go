@@ -0,0 +1,138 @@
+package apikit
+
+import (
+	"compress/gzip"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// compresses the response unless the handler already chose a Content-Encoding.
+// the status is held back until the first Write, Flush or the end of the handler, so
+// Content-Type can be sniffed from the uncompressed bytes even after WriteHeader
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz         *gzip.Writer
+	code       int
+	headerSent bool
+	compress   bool
+}
+
+// decides on compression and sends the held back status. p is the first chunk of the body,
+// nil if there is none yet
+func (g *gzipResponseWriter) sendHeader(p []byte) {
+	if g.headerSent {
+		return
+	}
+	g.headerSent = true
+
+	code := g.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+
+	h := g.Header()
+	if h.Get("Content-Encoding") == "" && bodyAllowedForStatus(code) {
+		if h.Get("Content-Type") == "" {
+			// the server would otherwise sniff the gzip output
+			if p != nil {
+				h.Set("Content-Type", http.DetectContentType(p))
+			} else {
+				h.Set("Content-Type", "application/octet-stream")
+			}
+		}
+
+		g.compress = true
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+	}
+
+	g.ResponseWriter.WriteHeader(code)
+}
+
+func bodyAllowedForStatus(code int) bool {
+	return code >= 200 && code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.headerSent || g.code != 0 {
+		return
+	}
+	g.code = code
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	g.sendHeader(p)
+	if !g.compress {
+		return g.ResponseWriter.Write(p)
+	}
+
+	if g.gz == nil {
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+	return g.gz.Write(p)
+}
+
+func (g *gzipResponseWriter) Flush() {
+	g.sendHeader(nil)
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+	if f, ok := g.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+func (g *gzipResponseWriter) close() {
+	if !g.headerSent {
+		// only a status and no body, nothing to compress
+		if g.code != 0 {
+			g.headerSent = true
+			g.ResponseWriter.WriteHeader(g.code)
+		}
+		return
+	}
+	if g.gz != nil {
+		g.gz.Close()
+	}
+}
+
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, enc := range strings.Split(value, ",") {
+			enc, _, _ = strings.Cut(enc, ";")
+			if strings.EqualFold(strings.TrimSpace(enc), "gzip") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Middleware for gzip compressing responses for clients that accept it. responses that
+// already have a Content-Encoding (compressed by the handler) are passed through untouched
+func GzipMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		SetVary(w, "Accept-Encoding")
+
+		if _, nested := w.(*gzipResponseWriter); nested {
+			log.Printf("WARNING: %v [%v] - GzipMiddleware applied twice, check the middleware stack\n", r.Method, r.URL.String())
+			next(w, r)
+			return
+		}
+
+		if !acceptsGzip(r) {
+			next(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+
+		next(gw, r)
+	}
+}
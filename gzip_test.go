@@ -0,0 +1,103 @@
+package apikit
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func gzipRequest() *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Encoding", "br, gzip;q=0.8")
+	return r
+}
+
+func gunzip(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("response not gzipped: %v", err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestGzipMiddlewareSniffsAfterWriteHeader(t *testing.T) {
+	h := GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("<html><body>created</body></html>"))
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, gzipRequest())
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/html") {
+		t.Fatalf("Content-Type = %q, want it sniffed from the uncompressed body", got)
+	}
+	if got := rec.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q", got)
+	}
+	if got := gunzip(t, rec); got != "<html><body>created</body></html>" {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestGzipMiddlewarePassThrough(t *testing.T) {
+	// already encoded by the handler
+	h := GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "br")
+		w.Write([]byte("raw"))
+	})
+	rec := httptest.NewRecorder()
+	h(rec, gzipRequest())
+	if rec.Header().Get("Content-Encoding") != "br" || rec.Body.String() != "raw" {
+		t.Fatalf("handler encoding overridden: %v %q", rec.Header(), rec.Body.String())
+	}
+
+	// client doesn't accept gzip
+	rec = httptest.NewRecorder()
+	GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("plain"))
+	})(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.String() != "plain" {
+		t.Fatal("compressed for a client without gzip")
+	}
+	if rec.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("Vary = %q", rec.Header().Get("Vary"))
+	}
+}
+
+func TestGzipMiddlewareStatusOnly(t *testing.T) {
+	rec := httptest.NewRecorder()
+	GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})(rec, gzipRequest())
+
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+		t.Fatalf("got %d %v, body %d bytes", rec.Code, rec.Header(), rec.Body.Len())
+	}
+}
+
+func TestGzipMiddlewareNestedCompressesOnce(t *testing.T) {
+	captureLog(t)
+
+	h := GzipMiddleware(GzipMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("once"))
+	}))
+	rec := httptest.NewRecorder()
+	h(rec, gzipRequest())
+
+	if got := gunzip(t, rec); got != "once" {
+		t.Fatalf("body = %q, want it compressed exactly once", got)
+	}
+}
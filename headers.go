@@ -0,0 +1,109 @@
+package apikit
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// approximate wire size of a header set, counting "Key: value\r\n" per value
+func headerSize(h http.Header) int {
+	size := 0
+	for k, vs := range h {
+		for _, v := range vs {
+			size += len(k) + len(v) + 4
+		}
+	}
+	return size
+}
+
+// Middleware for logging requests whose request or response headers exceed threshold bytes.
+// only the sizes are logged, never the header contents
+func HeaderSizeMiddleware(threshold int, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reqSize := headerSize(r.Header)
+
+		next(w, r)
+
+		respSize := headerSize(w.Header())
+		if reqSize > threshold || respSize > threshold {
+			log.Printf("%v [%v] - large headers: request %vB, response %vB\n", r.Method, r.URL.Path, reqSize, respSize)
+		}
+	}
+}
+
+// sets headers that keep the response out of every cache, for authenticated responses
+func NoCache(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store, no-cache")
+	w.Header().Set("Pragma", "no-cache")
+	w.Header().Set("Expires", "0")
+}
+
+// Middleware for applying NoCache to every response, e.g. behind CookieTokenMiddleware
+func NoCacheMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		NoCache(w)
+		next(w, r)
+	}
+}
+
+// sets X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset (Unix seconds)
+// so clients can pace themselves
+func SetRateLimitHeaders(w http.ResponseWriter, limit, remaining int, reset time.Time) {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+}
+
+// adds headers to Vary, keeping a single de-duplicated Vary header
+func SetVary(w http.ResponseWriter, headers ...string) {
+	var vary []string
+	seen := make(map[string]bool)
+
+	add := func(h string) {
+		h = strings.TrimSpace(h)
+		key := http.CanonicalHeaderKey(h)
+		if h == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		vary = append(vary, h)
+	}
+
+	for _, value := range w.Header().Values("Vary") {
+		for _, h := range strings.Split(value, ",") {
+			add(h)
+		}
+	}
+	for _, h := range headers {
+		add(h)
+	}
+
+	w.Header().Set("Vary", strings.Join(vary, ", "))
+}
+
+// Middleware for rejecting requests with more than maxCount cookies or Cookie headers
+// totalling more than maxBytes with 400. a limit <= 0 is not enforced
+func CookieLimitMiddleware(maxCount, maxBytes int, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		size := 0
+		for _, value := range r.Header.Values("Cookie") {
+			size += len(value)
+		}
+
+		if maxBytes > 0 && size > maxBytes {
+			Error(w, "cookies too large", http.StatusBadRequest)
+			return
+		}
+
+		// size is checked first so oversized headers are never parsed
+		if maxCount > 0 && len(r.Cookies()) > maxCount {
+			Error(w, "too many cookies", http.StatusBadRequest)
+			return
+		}
+
+		next(w, r)
+	}
+}
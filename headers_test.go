@@ -0,0 +1,116 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHeaderSizeMiddleware(t *testing.T) {
+	buf := captureLog(t)
+	h := HeaderSizeMiddleware(100, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Big", strings.Repeat("x", 200))
+	})
+
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !strings.Contains(buf.String(), "large headers") {
+		t.Fatalf("no log for an oversized response header, got %q", buf.String())
+	}
+
+	buf.Reset()
+	HeaderSizeMiddleware(100, okHandler)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("logged small headers: %q", buf.String())
+	}
+}
+
+func TestNoCacheMiddleware(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NoCacheMiddleware(okHandler)(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	for k, want := range map[string]string{
+		"Cache-Control": "no-store, no-cache",
+		"Pragma":        "no-cache",
+		"Expires":       "0",
+	} {
+		if got := rec.Header().Get(k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+}
+
+func TestSetRateLimitHeaders(t *testing.T) {
+	reset := time.Unix(1700000000, 0)
+	rec := httptest.NewRecorder()
+	SetRateLimitHeaders(rec, 100, 7, reset)
+
+	for k, want := range map[string]string{
+		"X-RateLimit-Limit":     "100",
+		"X-RateLimit-Remaining": "7",
+		"X-RateLimit-Reset":     "1700000000",
+	} {
+		if got := rec.Header().Get(k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+}
+
+func TestQuotaMiddlewareSendsRateLimitHeaders(t *testing.T) {
+	store := NewMemoryQuotaStore(5, time.Minute)
+	h := QuotaMiddleware(store, QuotaKeyFromHeader("X-Key"), okHandler)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Key", "k")
+	rec := httptest.NewRecorder()
+	h(rec, r)
+
+	if got := rec.Header().Get("X-RateLimit-Limit"); got != "5" {
+		t.Errorf("X-RateLimit-Limit = %q, want 5", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "4" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 4", got)
+	}
+	reset, _ := strconv.ParseInt(rec.Header().Get("X-RateLimit-Reset"), 10, 64)
+	if until := time.Until(time.Unix(reset, 0)); until <= 0 || until > time.Minute {
+		t.Errorf("X-RateLimit-Reset is %v away, want within the period", until)
+	}
+}
+
+func TestSetVary(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Add("Vary", "Accept-Encoding, origin")
+
+	SetVary(rec, "Origin", "Accept", "accept-encoding")
+
+	if got := rec.Header().Values("Vary"); len(got) != 1 || got[0] != "Accept-Encoding, origin, Accept" {
+		t.Fatalf("Vary = %v, want one merged value without duplicates", got)
+	}
+}
+
+func TestCookieLimitMiddleware(t *testing.T) {
+	h := CookieLimitMiddleware(2, 64, okHandler)
+
+	for _, tc := range []struct {
+		name   string
+		cookie string
+		want   int
+	}{
+		{"within limits", "a=1; b=2", http.StatusOK},
+		{"too many", "a=1; b=2; c=3", http.StatusBadRequest},
+		{"too large", "a=" + strings.Repeat("x", 64), http.StatusBadRequest},
+		{"none", "", http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.cookie != "" {
+			r.Header.Set("Cookie", tc.cookie)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package apikit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// proxies trusted to report the client address via X-Forwarded-For. immutable once parsed,
+// so it is safe to share between requests
+type TrustedProxies []*net.IPNet
+
+func ParseTrustedProxies(cidrs ...string) (TrustedProxies, error) {
+	return parseCIDRs(cidrs)
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolves the client's IP from RemoteAddr alone, for servers not behind a proxy
+func ClientIP(r *http.Request) net.IP {
+	return TrustedProxies(nil).ClientIP(r)
+}
+
+// resolves the client's IP. X-Forwarded-For is walked right to left only while the hops
+// are trusted proxies, so clients can't spoof their address. returns nil if unparseable
+func (tp TrustedProxies) ClientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !containsIP(tp, ip) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+
+		ip = hop
+		if !containsIP(tp, hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// Middleware for restricting access to clients within the given CIDRs (IPv4 or IPv6).
+// the client IP is resolved through proxies, which may be nil when there are none
+func AllowIPRanges(cidrs []string, proxies TrustedProxies, next http.HandlerFunc) http.HandlerFunc {
+	allowed, err := parseCIDRs(cidrs)
+	if err != nil {
+		panic(err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := proxies.ClientIP(r)
+		if ip == nil || !containsIP(allowed, ip) {
+			Forbidden(w, "ip_not_allowed")
+			return
+		}
+
+		next(w, r)
+	}
+}
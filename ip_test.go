@@ -0,0 +1,64 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowIPRanges(t *testing.T) {
+	h := AllowIPRanges([]string{"10.0.0.0/8", "2001:db8::/32"}, nil, okHandler)
+
+	for _, tc := range []struct {
+		remote string
+		want   int
+	}{
+		{"10.1.2.3:1234", http.StatusOK},
+		{"[2001:db8::1]:1234", http.StatusOK},
+		{"192.0.2.1:1234", http.StatusForbidden},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tc.remote
+		rec := httptest.NewRecorder()
+		h(rec, r)
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.remote, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestAllowIPRangesPanicsOnBadCIDR(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic for an invalid CIDR")
+		}
+	}()
+	AllowIPRanges([]string{"not-a-cidr"}, nil, okHandler)
+}
+
+func TestClientIPTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7, 10.0.0.2")
+
+	if got := proxies.ClientIP(r).String(); got != "198.51.100.7" {
+		t.Fatalf("ClientIP via trusted proxies = %s, want 198.51.100.7", got)
+	}
+
+	// without trusted proxies the header is ignored
+	if got := ClientIP(r).String(); got != "10.0.0.1" {
+		t.Fatalf("ClientIP = %s, want 10.0.0.1", got)
+	}
+
+	// a spoofed header from an untrusted peer is ignored too
+	r.RemoteAddr = "192.0.2.9:1234"
+	if got := proxies.ClientIP(r).String(); got != "192.0.2.9" {
+		t.Fatalf("ClientIP from untrusted peer = %s, want 192.0.2.9", got)
+	}
+}
@@ -0,0 +1,155 @@
+package apikit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const maskedValue = "***"
+
+// encodes v as the JSON response body, streaming it to the client
+func WriteJSON(w http.ResponseWriter, v any, code int) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	return json.NewEncoder(w).Encode(v)
+}
+
+// like WriteJSON but encodes into memory first so Content-Length can be set.
+// prefer WriteJSON for large payloads
+func WriteJSONBuffered(w http.ResponseWriter, v any, code int) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(code)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// replaces the values of the named fields, at any depth, with "***" so bodies can be logged
+// without leaking secrets. bodies that are not valid JSON are returned as is
+func MaskJSONFields(body []byte, fields []string) []byte {
+	if len(fields) == 0 {
+		return body
+	}
+
+	// UseNumber keeps large integers (e.g. IDs above 2^53) exactly as sent
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+
+	mask := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		mask[f] = true
+	}
+
+	// unlike json.Marshal, leave <, > and & as sent
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(maskValue(v, mask)); err != nil {
+		return body
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func maskValue(v any, mask map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if mask[k] {
+				v[k] = maskedValue
+			} else {
+				v[k] = maskValue(child, mask)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = maskValue(child, mask)
+		}
+	}
+	return v
+}
+
+// responds 201 Created with a Location header pointing at the new resource
+func Created(w http.ResponseWriter, location string, body any) error {
+	w.Header().Set("Location", location)
+	return WriteJSON(w, body, http.StatusCreated)
+}
+
+var ErrJSONTooDeep = errors.New("JSON nested too deeply")
+
+// decodes the JSON request body into dst
+func DecodeJSON(r *http.Request, dst any) error {
+	return json.NewDecoder(r.Body).Decode(dst)
+}
+
+// like DecodeJSON but first rejects bodies nested deeper than maxDepth with ErrJSONTooDeep.
+// bodies over maxBytes (<= 0 for no limit) fail with ErrBodyTooLarge
+func DecodeJSONMaxDepth(r *http.Request, dst any, maxDepth int, maxBytes int64) error {
+	body, err := readBody(r, maxBytes)
+	if err != nil {
+		return err
+	}
+
+	if err := checkJSONDepth(body, maxDepth); err != nil {
+		return err
+	}
+	return json.Unmarshal(body, dst)
+}
+
+// scans the tokens of data without decoding it, failing once arrays/objects nest past maxDepth
+func checkJSONDepth(data []byte, maxDepth int) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	depth := 0
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+			if depth > maxDepth {
+				return ErrJSONTooDeep
+			}
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+}
+
+// Middleware for rejecting JSON bodies nested deeper than maxDepth with 400, and bodies
+// over maxBytes (<= 0 for no limit) with 413. the body is left readable for the handler
+func JSONDepthLimit(maxDepth int, maxBytes int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := readBody(r, maxBytes)
+		if err != nil {
+			writeReadBodyError(w, err)
+			return
+		}
+
+		if err := checkJSONDepth(body, maxDepth); err == ErrJSONTooDeep {
+			Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		next(w, r)
+	}
+}
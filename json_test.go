@@ -0,0 +1,109 @@
+package apikit
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMaskJSONFields(t *testing.T) {
+	body := []byte(`{"user":"ann","password":"hunter2","nested":[{"token":"t","id":12345678901234567890}],"note":"<b>&</b>"}`)
+
+	got := string(MaskJSONFields(body, []string{"password", "token"}))
+	want := `{"nested":[{"id":12345678901234567890,"token":"***"}],"note":"<b>&</b>","password":"***","user":"ann"}`
+	if got != want {
+		t.Fatalf("MaskJSONFields =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMaskJSONFieldsInvalidJSON(t *testing.T) {
+	body := []byte(`not json`)
+	if got := MaskJSONFields(body, []string{"password"}); string(got) != string(body) {
+		t.Fatalf("MaskJSONFields = %q, want body unchanged", got)
+	}
+}
+
+func TestWriteJSONBuffered(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := WriteJSONBuffered(rec, map[string]string{"hello": "world"}, http.StatusAccepted); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", rec.Code)
+	}
+	if got, want := rec.Header().Get("Content-Length"), strconv.Itoa(rec.Body.Len()); got != want {
+		t.Fatalf("Content-Length = %q, want %q", got, want)
+	}
+	if got := rec.Body.String(); got != "{\"hello\":\"world\"}\n" {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestCreated(t *testing.T) {
+	rec := httptest.NewRecorder()
+	if err := Created(rec, "/widgets/7", map[string]int{"id": 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "/widgets/7" {
+		t.Fatalf("Location = %q, want /widgets/7", got)
+	}
+	if got := rec.Body.String(); got != "{\"id\":7}\n" {
+		t.Fatalf("body = %q", got)
+	}
+}
+
+func TestDecodeJSONMaxDepth(t *testing.T) {
+	var v map[string]any
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":{"b":1}}`))
+	if err := DecodeJSONMaxDepth(r, &v, 2, 0); err != nil {
+		t.Fatalf("depth 2: %v", err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":{"b":[1]}}`))
+	if err := DecodeJSONMaxDepth(r, &v, 2, 0); err != ErrJSONTooDeep {
+		t.Fatalf("depth 3: err = %v, want ErrJSONTooDeep", err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":"`+strings.Repeat("x", 64)+`"}`))
+	if err := DecodeJSONMaxDepth(r, &v, 2, 16); !errors.Is(err, ErrBodyTooLarge) {
+		t.Fatalf("oversized: err = %v, want ErrBodyTooLarge", err)
+	}
+}
+
+func TestJSONDepthLimit(t *testing.T) {
+	var seen string
+	h := JSONDepthLimit(2, 64, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+	})
+
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{`[[1]]`, http.StatusOK},
+		{`[[[1]]]`, http.StatusBadRequest},
+		{`"` + strings.Repeat("x", 64) + `"`, http.StatusRequestEntityTooLarge},
+	} {
+		seen = ""
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)))
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.body, rec.Code, tc.want)
+		}
+		if tc.want == http.StatusOK && seen != tc.body {
+			t.Errorf("handler read %q, want the body left intact", seen)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package apikit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// largest page[size] accepted by ParseJSONAPIParams
+var MaxJSONAPIPageSize = 100
+
+// the JSON:API (jsonapi.org) query parameters of a request. zero values mean absent
+type JSONAPIParams struct {
+	PageSize   int
+	PageNumber int
+	// filter[status]=open becomes Filter["status"] = "open"
+	Filter map[string]string
+	Sort   []SortField
+}
+
+// extracts ?page[size]=, ?page[number]=, ?filter[...]= and ?sort= from the query.
+// errors (malformed brackets, unknown page keys, bad sizes) are 400-worthy
+func ParseJSONAPIParams(r *http.Request) (JSONAPIParams, error) {
+	params := JSONAPIParams{Filter: map[string]string{}}
+
+	for key, values := range r.URL.Query() {
+		if len(values) == 0 {
+			continue
+		}
+		value := values[0]
+
+		family, member, bracketed, err := splitBracketKey(key)
+		if err != nil {
+			return JSONAPIParams{}, err
+		}
+
+		switch {
+		case family == "sort" && !bracketed:
+			params.Sort = parseSortSpec(value)
+		case family == "filter" && bracketed:
+			params.Filter[member] = value
+		case family == "page" && bracketed:
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return JSONAPIParams{}, fmt.Errorf("%v must be a positive integer", key)
+			}
+
+			switch member {
+			case "size":
+				if n > MaxJSONAPIPageSize {
+					return JSONAPIParams{}, fmt.Errorf("%v must be at most %v", key, MaxJSONAPIPageSize)
+				}
+				params.PageSize = n
+			case "number":
+				params.PageNumber = n
+			default:
+				return JSONAPIParams{}, fmt.Errorf("unknown page parameter %v", key)
+			}
+		case family == "page" || family == "filter":
+			return JSONAPIParams{}, fmt.Errorf("%v requires a bracketed member, e.g. %v[name]", key, family)
+		}
+	}
+	return params, nil
+}
+
+// splits "page[size]" into ("page", "size", true). keys without brackets return bracketed false
+func splitBracketKey(key string) (family, member string, bracketed bool, err error) {
+	open := strings.IndexByte(key, '[')
+	if open < 0 {
+		if strings.ContainsRune(key, ']') {
+			return "", "", false, fmt.Errorf("malformed parameter %q", key)
+		}
+		return key, "", false, nil
+	}
+
+	member = key[open+1:]
+	if open == 0 || !strings.HasSuffix(member, "]") {
+		return "", "", false, fmt.Errorf("malformed parameter %q", key)
+	}
+
+	member = member[:len(member)-1]
+	if member == "" || strings.ContainsAny(member, "[]") {
+		return "", "", false, fmt.Errorf("malformed parameter %q", key)
+	}
+	return key[:open], member, true, nil
+}
@@ -0,0 +1,51 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONAPIParams(t *testing.T) {
+	q := url.Values{
+		"page[size]":     {"25"},
+		"page[number]":   {"3"},
+		"filter[status]": {"open"},
+		"sort":           {"-created,title"},
+		"unrelated":      {"x"},
+	}
+	r := httptest.NewRequest(http.MethodGet, "/articles?"+q.Encode(), nil)
+
+	got, err := ParseJSONAPIParams(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := JSONAPIParams{
+		PageSize:   25,
+		PageNumber: 3,
+		Filter:     map[string]string{"status": "open"},
+		Sort:       []SortField{{Field: "created", Descending: true}, {Field: "title"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestParseJSONAPIParamsErrors(t *testing.T) {
+	for _, query := range []string{
+		"page[size]=0",
+		"page[size]=1000",
+		"page[offset]=1",
+		"page=1",
+		"filter[]=x",
+		"filter[a[b]]=x",
+		"page]size[=1",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		if _, err := ParseJSONAPIParams(r); err == nil {
+			t.Errorf("%s: no error", query)
+		}
+	}
+}
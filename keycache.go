@@ -0,0 +1,92 @@
+package apikit
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("no key with that kid")
+
+// fetches the current key set (e.g. from a JWKS endpoint), keyed by kid
+type KeySetFetcher func() (map[string]any, error)
+
+// caches verification keys by kid so repeated tokens don't each trigger a key lookup.
+// an unknown kid refetches the whole set (at most once per minRefresh), and so does any
+// lookup once the set is older than maxAge, so keys rotated out of it stop verifying
+// within maxAge even while tokens keep using their kid
+type KeyCache struct {
+	mu          sync.RWMutex
+	fetch       KeySetFetcher
+	keys        map[string]any
+	lastFetch   time.Time
+	minRefresh  time.Duration
+	maxAge      time.Duration
+	refreshLock sync.Mutex
+	now         func() time.Time
+}
+
+func NewKeyCache(fetch KeySetFetcher, minRefresh, maxAge time.Duration) *KeyCache {
+	if maxAge <= 0 {
+		panic("NewKeyCache: maxAge must be positive")
+	}
+	return &KeyCache{fetch: fetch, keys: map[string]any{}, minRefresh: minRefresh, maxAge: maxAge, now: time.Now}
+}
+
+// returns the key for kid, refetching the key set on a miss or once it is older than maxAge.
+// a failed refetch of a stale set is returned rather than trusting keys that may be revoked
+func (c *KeyCache) Key(kid string) (any, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	stale := c.now().Sub(c.lastFetch) >= c.maxAge
+	c.mu.RUnlock()
+	if ok && !stale {
+		return key, nil
+	}
+
+	minAge := c.minRefresh
+	if stale {
+		minAge = c.maxAge
+	}
+	if err := c.refresh(minAge); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if key, ok := c.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// refetches the key set now, e.g. on a schedule to evict rotated keys promptly
+func (c *KeyCache) Refresh() error {
+	return c.refresh(0)
+}
+
+// refetches the key set unless it is younger than minAge
+func (c *KeyCache) refresh(minAge time.Duration) error {
+	// one fetch at a time, concurrent lookups wait for it instead of fetching again
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+
+	c.mu.RLock()
+	recent := c.now().Sub(c.lastFetch) < minAge
+	c.mu.RUnlock()
+	if recent {
+		return nil
+	}
+
+	keys, err := c.fetch()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// replace wholesale so keys missing from the new set are invalidated
+	c.keys = keys
+	c.lastFetch = c.now()
+	return nil
+}
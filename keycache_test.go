@@ -0,0 +1,144 @@
+package apikit
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a KeySetFetcher over a mutable key set that counts its fetches
+type fakeKeySet struct {
+	mu      sync.Mutex
+	keys    map[string]any
+	fetches int
+}
+
+func (f *fakeKeySet) fetch() (map[string]any, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fetches++
+	keys := make(map[string]any, len(f.keys))
+	for k, v := range f.keys {
+		keys[k] = v
+	}
+	return keys, nil
+}
+
+func TestKeyCache(t *testing.T) {
+	set := &fakeKeySet{keys: map[string]any{"k1": "key-1"}}
+	cache := NewKeyCache(set.fetch, time.Hour, 24*time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if key, err := cache.Key("k1"); err != nil || key != "key-1" {
+			t.Fatalf("Key(k1) = %v, %v", key, err)
+		}
+	}
+	if set.fetches != 1 {
+		t.Fatalf("%d fetches for repeated hits, want 1", set.fetches)
+	}
+
+	// misses within minRefresh don't refetch
+	if _, err := cache.Key("nope"); err != ErrUnknownKey {
+		t.Fatalf("Key(nope) err = %v, want ErrUnknownKey", err)
+	}
+	if set.fetches != 1 {
+		t.Fatalf("%d fetches after a miss within minRefresh, want 1", set.fetches)
+	}
+}
+
+func TestKeyCacheRotation(t *testing.T) {
+	set := &fakeKeySet{keys: map[string]any{"k1": "key-1"}}
+	cache := NewKeyCache(set.fetch, 0, time.Hour)
+	cache.Key("k1")
+
+	// k2 is published and k1 rotated out
+	set.mu.Lock()
+	set.keys = map[string]any{"k2": "key-2"}
+	set.mu.Unlock()
+
+	if key, err := cache.Key("k2"); err != nil || key != "key-2" {
+		t.Fatalf("Key(k2) = %v, %v; want the new key after a refetch", key, err)
+	}
+	if _, err := cache.Key("k1"); err != ErrUnknownKey {
+		t.Fatalf("Key(k1) err = %v, want the rotated key gone", err)
+	}
+}
+
+func TestKeyCacheConcurrentMisses(t *testing.T) {
+	set := &fakeKeySet{keys: map[string]any{"k1": "key-1"}}
+	cache := NewKeyCache(set.fetch, time.Hour, 24*time.Hour)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Key("k1")
+		}()
+	}
+	wg.Wait()
+
+	if set.fetches != 1 {
+		t.Fatalf("%d fetches for concurrent misses, want 1", set.fetches)
+	}
+}
+
+func TestKeyCacheMaxAge(t *testing.T) {
+	set := &fakeKeySet{keys: map[string]any{"k1": "key-1"}}
+	cache := NewKeyCache(set.fetch, time.Minute, time.Hour)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	if _, err := cache.Key("k1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// k1 is rotated out, but every lookup hits until the set ages out
+	set.mu.Lock()
+	set.keys = map[string]any{"k2": "key-2"}
+	set.mu.Unlock()
+
+	now = now.Add(59 * time.Minute)
+	if key, err := cache.Key("k1"); err != nil || key != "key-1" {
+		t.Fatalf("Key(k1) before maxAge = %v, %v; want the cached key", key, err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := cache.Key("k1"); err != ErrUnknownKey {
+		t.Fatalf("Key(k1) after maxAge err = %v, want the rotated key rejected", err)
+	}
+	if set.fetches != 2 {
+		t.Fatalf("%d fetches, want 2", set.fetches)
+	}
+}
+
+func TestKeyCacheStaleFetchError(t *testing.T) {
+	fail := false
+	errFetch := errors.New("jwks down")
+	cache := NewKeyCache(func() (map[string]any, error) {
+		if fail {
+			return nil, errFetch
+		}
+		return map[string]any{"k1": "key-1"}, nil
+	}, time.Minute, time.Hour)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.Key("k1")
+	fail = true
+	now = now.Add(time.Hour)
+	if _, err := cache.Key("k1"); err != errFetch {
+		t.Fatalf("Key on a stale set with a failing fetch err = %v, want %v", err, errFetch)
+	}
+}
+
+func TestNewKeyCacheRejectsMaxAge(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewKeyCache with a zero maxAge did not panic")
+		}
+	}()
+	NewKeyCache(func() (map[string]any, error) { return nil, nil }, 0, 0)
+}
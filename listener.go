@@ -0,0 +1,67 @@
+package apikit
+
+import (
+	"net"
+	"sync"
+)
+
+// caps the number of simultaneously open connections accepted from a net.Listener.
+// Accept blocks while the cap is reached until a connection closes, the same as
+// golang.org/x/net/netutil.LimitListener
+type limitListener struct {
+	net.Listener
+	sem  chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// wraps l so that at most n connections are open at once. note that idle keep-alive
+// connections hold a slot too, so pair this with a server IdleTimeout. panics if n < 1
+func LimitListener(l net.Listener, n int) net.Listener {
+	if n < 1 {
+		panic("LimitListener: n must be at least 1")
+	}
+	return &limitListener{Listener: l, sem: make(chan struct{}, n), done: make(chan struct{})}
+}
+
+// a listener on addr that accepts at most n connections at once, ready for http.Serve
+func ListenLimited(network, addr string, n int) (net.Listener, error) {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return LimitListener(l, n), nil
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	select {
+	case l.sem <- struct{}{}:
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+
+	c, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitListenerConn{Conn: c, release: func() { <-l.sem }}, nil
+}
+
+func (l *limitListener) Close() error {
+	err := l.Listener.Close()
+	l.once.Do(func() { close(l.done) })
+	return err
+}
+
+type limitListenerConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitListenerConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
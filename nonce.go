@@ -0,0 +1,147 @@
+package apikit
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const NonceHeader = "X-Nonce"
+
+var (
+	ErrNonceUnknown = errors.New("unknown nonce")
+	ErrNonceReused  = errors.New("nonce already used")
+)
+
+// backing store for server issued single-use nonces
+type NonceStore interface {
+	Save(nonce string) error
+	// marks nonce as used. returns ErrNonceReused if it already was, ErrNonceUnknown if it
+	// was never issued (or has expired)
+	Consume(nonce string) error
+}
+
+// issues a fresh nonce into store and sets it in the X-Nonce response header. call it from
+// the handlers (e.g. a form or session endpoint) whose responses should arm a following
+// mutating request
+func IssueNonce(w http.ResponseWriter, store NonceStore) (string, error) {
+	nonce, err := newNonce()
+	if err != nil {
+		return "", err
+	}
+	if err := store.Save(nonce); err != nil {
+		return "", err
+	}
+
+	w.Header().Set(NonceHeader, nonce)
+	return nonce, nil
+}
+
+// Middleware for requiring a server issued nonce (see IssueNonce) on mutating requests, which
+// must echo it back in X-Nonce. safe methods pass through untouched.
+// unknown nonces are rejected with 403, reused ones with 409
+func NonceMiddleware(store NonceStore, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next(w, r)
+			return
+		}
+
+		nonce := r.Header.Get(NonceHeader)
+		if nonce == "" {
+			Forbidden(w, "nonce_missing")
+			return
+		}
+
+		switch err := store.Consume(nonce); err {
+		case nil:
+			next(w, r)
+		case ErrNonceReused:
+			Error(w, err.Error(), http.StatusConflict)
+		case ErrNonceUnknown:
+			Forbidden(w, "nonce_unknown")
+		default:
+			Error(w, "", http.StatusInternalServerError)
+		}
+	}
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type nonceEntry struct {
+	expires time.Time
+	used    bool
+}
+
+// in-memory NonceStore whose nonces expire after ttl, holding at most capacity of them.
+// when full the oldest nonce is evicted, so it then fails as unknown
+type MemoryNonceStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]nonceEntry
+	// nonces in the order saved, which is also expiry order. ring buffer of len capacity
+	order []string
+	head  int
+	count int
+}
+
+func NewMemoryNonceStore(ttl time.Duration, capacity int) *MemoryNonceStore {
+	if capacity <= 0 {
+		panic("NewMemoryNonceStore: capacity must be positive")
+	}
+	return &MemoryNonceStore{ttl: ttl, entries: make(map[string]nonceEntry), order: make([]string, capacity)}
+}
+
+func (s *MemoryNonceStore) Save(nonce string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// only expired nonces at the front need dropping, not a scan of the whole store
+	now := time.Now()
+	for s.count > 0 && now.After(s.entries[s.order[s.head]].expires) {
+		s.popOldest()
+	}
+	if s.count == len(s.order) {
+		s.popOldest()
+	}
+
+	s.order[(s.head+s.count)%len(s.order)] = nonce
+	s.count++
+	s.entries[nonce] = nonceEntry{expires: now.Add(s.ttl)}
+	return nil
+}
+
+// must be called with mu held
+func (s *MemoryNonceStore) popOldest() {
+	delete(s.entries, s.order[s.head])
+	s.order[s.head] = ""
+	s.head = (s.head + 1) % len(s.order)
+	s.count--
+}
+
+func (s *MemoryNonceStore) Consume(nonce string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[nonce]
+	if !ok || time.Now().After(e.expires) {
+		return ErrNonceUnknown
+	}
+	if e.used {
+		return ErrNonceReused
+	}
+
+	e.used = true
+	s.entries[nonce] = e
+	return nil
+}
@@ -0,0 +1,86 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNonceMiddleware(t *testing.T) {
+	store := NewMemoryNonceStore(time.Minute, 16)
+	h := NonceMiddleware(store, okHandler)
+
+	// safe methods pass without issuing anything
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get(NonceHeader) != "" {
+		t.Fatalf("GET: status = %d, nonce %q; want 200 and no nonce", rec.Code, rec.Header().Get(NonceHeader))
+	}
+
+	issued := httptest.NewRecorder()
+	nonce, err := IssueNonce(issued, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued.Header().Get(NonceHeader) != nonce {
+		t.Fatal("IssueNonce did not set the header")
+	}
+
+	for i, want := range []int{http.StatusOK, http.StatusConflict} {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Header.Set(NonceHeader, nonce)
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != want {
+			t.Fatalf("use %d: status = %d, want %d", i+1, rec.Code, want)
+		}
+	}
+
+	for name, value := range map[string]string{"missing": "", "unknown": "deadbeef"} {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if value != "" {
+			r.Header.Set(NonceHeader, value)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s: status = %d, want 403", name, rec.Code)
+		}
+	}
+}
+
+func TestMemoryNonceStoreEvictsOldest(t *testing.T) {
+	store := NewMemoryNonceStore(time.Minute, 2)
+	store.Save("a")
+	store.Save("b")
+	store.Save("c")
+
+	if err := store.Consume("a"); err != ErrNonceUnknown {
+		t.Fatalf("evicted nonce: err = %v, want ErrNonceUnknown", err)
+	}
+	for _, n := range []string{"b", "c"} {
+		if err := store.Consume(n); err != nil {
+			t.Fatalf("%s: %v", n, err)
+		}
+	}
+	if len(store.entries) > 2 {
+		t.Fatalf("store holds %d nonces, capacity 2", len(store.entries))
+	}
+}
+
+func TestMemoryNonceStoreExpiry(t *testing.T) {
+	store := NewMemoryNonceStore(time.Millisecond, 4)
+	store.Save("a")
+	time.Sleep(5 * time.Millisecond)
+
+	if err := store.Consume("a"); err != ErrNonceUnknown {
+		t.Fatalf("expired nonce: err = %v, want ErrNonceUnknown", err)
+	}
+
+	// saving drops the expired entry
+	store.Save("b")
+	if _, ok := store.entries["a"]; ok {
+		t.Fatal("expired nonce still stored")
+	}
+}
@@ -0,0 +1,57 @@
+package apikit
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidOneTimeToken = errors.New("invalid one-time token")
+	ErrOneTimeTokenExpired = errors.New("one-time token expired")
+)
+
+// an HMAC signed "<payload>.<expiry>.<signature>" token for e.g. email verification or password
+// reset links. expiry is checked by VerifyOneTimeToken, single use must be enforced by the caller
+func GenerateOneTimeToken(secret []byte, payload string, ttl time.Duration) string {
+	signed := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		strconv.FormatInt(time.Now().Add(ttl).Unix(), 10)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(hmacSHA256(secret, []byte(signed)))
+}
+
+// returns the payload of a token made by GenerateOneTimeToken
+func VerifyOneTimeToken(secret []byte, token string) (string, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return "", ErrInvalidOneTimeToken
+	}
+	signed := token[:i]
+
+	sig, err := base64.RawURLEncoding.DecodeString(token[i+1:])
+	if err != nil || !hmac.Equal(sig, hmacSHA256(secret, []byte(signed))) {
+		return "", ErrInvalidOneTimeToken
+	}
+
+	encodedPayload, expiry, ok := strings.Cut(signed, ".")
+	if !ok {
+		return "", ErrInvalidOneTimeToken
+	}
+
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return "", ErrInvalidOneTimeToken
+	}
+	if time.Now().After(time.Unix(unix, 0)) {
+		return "", ErrOneTimeTokenExpired
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", ErrInvalidOneTimeToken
+	}
+	return string(payload), nil
+}
@@ -0,0 +1,29 @@
+package apikit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOneTimeToken(t *testing.T) {
+	secret := []byte("s3cret")
+	token := GenerateOneTimeToken(secret, "user:42:reset", time.Hour)
+
+	if got, err := VerifyOneTimeToken(secret, token); err != nil || got != "user:42:reset" {
+		t.Fatalf("got %q, %v; want the payload", got, err)
+	}
+	if _, err := VerifyOneTimeToken([]byte("other"), token); err != ErrInvalidOneTimeToken {
+		t.Errorf("wrong secret: err = %v, want ErrInvalidOneTimeToken", err)
+	}
+	if _, err := VerifyOneTimeToken(secret, "x"+token); err != ErrInvalidOneTimeToken {
+		t.Errorf("tampered: err = %v, want ErrInvalidOneTimeToken", err)
+	}
+	if _, err := VerifyOneTimeToken(secret, "garbage"); err != ErrInvalidOneTimeToken {
+		t.Errorf("garbage: err = %v, want ErrInvalidOneTimeToken", err)
+	}
+
+	expired := GenerateOneTimeToken(secret, "user:42:reset", -time.Minute)
+	if _, err := VerifyOneTimeToken(secret, expired); err != ErrOneTimeTokenExpired {
+		t.Errorf("expired: err = %v, want ErrOneTimeTokenExpired", err)
+	}
+}
@@ -0,0 +1,177 @@
+package apikit
+
+import (
+	"log"
+	"net/http"
+)
+
+// returns the request's Origin if it is one of allowed. pass it to SetHttpOnlyCookie
+// so credentialed cookies are only issued with CORS headers for a known origin
+func AllowedOrigin(r *http.Request, allowed []string) (string, bool) {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !contains(allowed, origin) {
+		return "", false
+	}
+	return origin, true
+}
+
+// Middleware for rejecting requests from origins outside allowed with 403, e.g. in front of
+// login handlers that issue credentialed cookies
+func RequireOrigin(allowed []string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := AllowedOrigin(r, allowed); !ok {
+			Forbidden(w, "origin_not_allowed")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// implemented by writers that want to know which origin each credentialed cookie was issued for
+type cookieOriginRecorder interface {
+	recordCookieOrigin(setCookie, origin string)
+}
+
+// finds a cookieOriginRecorder in w or the writers it wraps
+func findCookieOriginRecorder(w http.ResponseWriter) (cookieOriginRecorder, bool) {
+	for {
+		if rec, ok := w.(cookieOriginRecorder); ok {
+			return rec, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil, false
+		}
+		w = u.Unwrap()
+	}
+}
+
+type issuedCookie struct {
+	setCookie string
+	origin    string
+}
+
+// makes the CORS headers coherent just before they are sent
+type corsFinalizingWriter struct {
+	http.ResponseWriter
+	cookies   []issuedCookie
+	finalized bool
+}
+
+func (c *corsFinalizingWriter) recordCookieOrigin(setCookie, origin string) {
+	c.cookies = append(c.cookies, issuedCookie{setCookie, origin})
+}
+
+func (c *corsFinalizingWriter) finalize() {
+	if c.finalized {
+		return
+	}
+	c.finalized = true
+
+	h := c.Header()
+
+	// several layers may have added an origin, the innermost (last) one wins
+	if origins := h.Values("Access-Control-Allow-Origin"); len(origins) > 1 {
+		h.Set("Access-Control-Allow-Origin", origins[len(origins)-1])
+	}
+
+	if len(h.Values("Access-Control-Allow-Credentials")) > 0 {
+		// browsers reject credentials with a wildcard origin
+		if h.Get("Access-Control-Allow-Origin") == "*" {
+			h.Del("Access-Control-Allow-Credentials")
+		} else {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+	}
+
+	c.dropConflictingCookies(h.Get("Access-Control-Allow-Origin"))
+}
+
+// removes cookies issued by SetHttpOnlyCookie for an origin other than the one finally allowed.
+// the browser would discard them anyway, dropping them makes the conflict visible in the log
+func (c *corsFinalizingWriter) dropConflictingCookies(origin string) {
+	h := c.Header()
+	for _, ic := range c.cookies {
+		if ic.origin == origin {
+			continue
+		}
+
+		lines := h.Values("Set-Cookie")
+		for i, line := range lines {
+			if line == ic.setCookie {
+				log.Printf("CoherentCORSMiddleware: dropping cookie issued for origin %q, response allows %q\n", ic.origin, origin)
+				h["Set-Cookie"] = append(lines[:i:i], lines[i+1:]...)
+				break
+			}
+		}
+	}
+	if len(h.Values("Set-Cookie")) == 0 {
+		h.Del("Set-Cookie")
+	}
+}
+
+func (c *corsFinalizingWriter) WriteHeader(code int) {
+	c.finalize()
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *corsFinalizingWriter) Write(p []byte) (int, error) {
+	c.finalize()
+	return c.ResponseWriter.Write(p)
+}
+
+func (c *corsFinalizingWriter) Flush() {
+	c.finalize()
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (c *corsFinalizingWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// Middleware for keeping CORS headers coherent when several layers set them, e.g. cookie
+// issuance (SetHttpOnlyCookie) and CORS handling: a single Access-Control-Allow-Origin,
+// the last one set, and credentials only alongside a specific origin. cookies that
+// SetHttpOnlyCookie issued for a different origin than the final one are logged and dropped.
+// should wrap both
+func CoherentCORSMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cw := &corsFinalizingWriter{ResponseWriter: w}
+		next(cw, r)
+		cw.finalize()
+	}
+}
+
+// Middleware for rejecting cross-site mutating requests that carry cookies, based on the
+// Sec-Fetch-Site header modern browsers send. requests without the header (older clients)
+// pass when allowMissing is set and are rejected otherwise
+func RequireSameSite(allowMissing bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next(w, r)
+			return
+		}
+
+		if r.Header.Get("Cookie") == "" { // not cookie authenticated, nothing to forge
+			next(w, r)
+			return
+		}
+
+		switch r.Header.Get("Sec-Fetch-Site") {
+		case "same-origin", "same-site", "none":
+			next(w, r)
+		case "":
+			if !allowMissing {
+				Forbidden(w, "fetch_site_missing")
+				return
+			}
+			next(w, r)
+		default:
+			Forbidden(w, "cross_site_request")
+		}
+	}
+}
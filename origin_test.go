@@ -0,0 +1,136 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequireOrigin(t *testing.T) {
+	allowed := []string{"https://app.example"}
+	var issued string
+	h := RequireOrigin(allowed, func(w http.ResponseWriter, r *http.Request) {
+		origin, _ := AllowedOrigin(r, allowed)
+		issued = origin
+		SetHttpOnlyCookie(w, "sid", "abc", 60, origin)
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/login", nil)
+	r.Header.Set("Origin", "https://app.example")
+	rec := httptest.NewRecorder()
+	h(rec, r)
+	if rec.Code != http.StatusOK || issued != "https://app.example" {
+		t.Fatalf("allowed origin: status = %d, origin = %q", rec.Code, issued)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example" {
+		t.Fatalf("Access-Control-Allow-Origin = %q", got)
+	}
+
+	for _, origin := range []string{"https://evil.example", ""} {
+		r := httptest.NewRequest(http.MethodPost, "/login", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("origin %q: status = %d, want 403", origin, rec.Code)
+		}
+		if len(rec.Result().Cookies()) != 0 {
+			t.Errorf("origin %q: cookie issued", origin)
+		}
+	}
+}
+
+func TestCoherentCORSTwoLayers(t *testing.T) {
+	buf := captureLog(t)
+
+	// an inner layer issues a cookie for one origin, an outer CORS layer then settles on another
+	inner := func(w http.ResponseWriter, r *http.Request) {
+		SetHttpOnlyCookie(w, "sid", "abc", 60, "https://a.example")
+		SetHttpOnlyCookie(w, "csrf", "xyz", 60, "https://b.example")
+		w.Header().Add("Set-Cookie", "plain=1")
+		w.Write([]byte("ok"))
+	}
+	h := CoherentCORSMiddleware(inner)
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := rec.Header().Values("Access-Control-Allow-Origin"); len(got) != 1 || got[0] != "https://b.example" {
+		t.Fatalf("Access-Control-Allow-Origin = %v, want [https://b.example]", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Fatalf("Access-Control-Allow-Credentials = %q", got)
+	}
+
+	var names []string
+	for _, c := range rec.Result().Cookies() {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "csrf,plain" {
+		t.Fatalf("cookies = %v, want the one for the final origin and the unrelated one", names)
+	}
+	if !strings.Contains(buf.String(), "dropping cookie issued for origin") {
+		t.Fatalf("conflict not logged, got %q", buf.String())
+	}
+}
+
+func TestCoherentCORSWildcardDropsCredentials(t *testing.T) {
+	h := CoherentCORSMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Access-Control-Allow-Origin", "https://a.example")
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+		w.Header().Add("Access-Control-Allow-Origin", "*")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodOptions, "/", nil))
+
+	if got := rec.Header().Values("Access-Control-Allow-Origin"); len(got) != 1 || got[0] != "*" {
+		t.Fatalf("Access-Control-Allow-Origin = %v, want [*]", got)
+	}
+	if rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatal("credentials allowed alongside a wildcard origin")
+	}
+}
+
+func TestRequireSameSite(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		cookie       bool
+		fetchSite    string
+		allowMissing bool
+		want         int
+	}{
+		{"safe method cross-site", http.MethodGet, true, "cross-site", false, http.StatusOK},
+		{"no cookie cross-site", http.MethodPost, false, "cross-site", false, http.StatusOK},
+		{"same-origin", http.MethodPost, true, "same-origin", false, http.StatusOK},
+		{"same-site", http.MethodPost, true, "same-site", false, http.StatusOK},
+		{"user initiated", http.MethodPost, true, "none", false, http.StatusOK},
+		{"cross-site", http.MethodPost, true, "cross-site", true, http.StatusForbidden},
+		{"missing header allowed", http.MethodPost, true, "", true, http.StatusOK},
+		{"missing header rejected", http.MethodDelete, true, "", false, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", nil)
+			if tt.cookie {
+				r.AddCookie(&http.Cookie{Name: "token", Value: "x"})
+			}
+			if tt.fetchSite != "" {
+				r.Header.Set("Sec-Fetch-Site", tt.fetchSite)
+			}
+
+			rec := httptest.NewRecorder()
+			RequireSameSite(tt.allowMissing, okHandler)(rec, r)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
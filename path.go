@@ -0,0 +1,47 @@
+package apikit
+
+import (
+	"net/http"
+	"strings"
+)
+
+func collapseSlashes(p string) string {
+	if !strings.Contains(p, "//") {
+		return p
+	}
+
+	var b strings.Builder
+	b.Grow(len(p))
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' && i > 0 && p[i-1] == '/' {
+			continue
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String()
+}
+
+// Middleware for collapsing repeated slashes in the request path before routing.
+// when redirect is set, GET and HEAD requests are redirected to the cleaned path,
+// everything else (or everything, when redirect is unset) is rewritten in place
+func CollapseSlashesMiddleware(redirect bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cleaned := collapseSlashes(r.URL.Path)
+		if cleaned == r.URL.Path {
+			next(w, r)
+			return
+		}
+
+		if redirect && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+			u := *r.URL
+			u.Path = cleaned
+			u.RawPath = collapseSlashes(u.RawPath)
+			http.Redirect(w, r, u.RequestURI(), http.StatusMovedPermanently)
+			return
+		}
+
+		r.URL.Path = cleaned
+		r.URL.RawPath = collapseSlashes(r.URL.RawPath)
+		next(w, r)
+	}
+}
@@ -0,0 +1,43 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCollapseSlashesRewrite(t *testing.T) {
+	var gotPath string
+	h := CollapseSlashesMiddleware(false, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+	})
+
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "//users///7", nil))
+	if gotPath != "/users/7" {
+		t.Fatalf("path = %q, want /users/7", gotPath)
+	}
+}
+
+func TestCollapseSlashesRedirect(t *testing.T) {
+	h := CollapseSlashesMiddleware(true, okHandler)
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/a//b?x=1", nil))
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("status = %d, want 301", rec.Code)
+	}
+	if got := rec.Header().Get("Location"); got != "/a/b?x=1" {
+		t.Fatalf("Location = %q, want /a/b?x=1", got)
+	}
+
+	// non-idempotent methods are rewritten rather than redirected
+	var gotPath string
+	h = CollapseSlashesMiddleware(true, func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+	})
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/a//b", nil))
+	if rec.Code != http.StatusOK || gotPath != "/a/b" {
+		t.Fatalf("POST: status = %d, path = %q; want 200, /a/b", rec.Code, gotPath)
+	}
+}
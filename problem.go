@@ -0,0 +1,82 @@
+package apikit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// an error carrying the HTTP status it should be reported with
+type StatusError struct {
+	Code int
+	Msg  string
+}
+
+func (e *StatusError) Error() string {
+	return e.Msg
+}
+
+// an RFC 7807 problem details document
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// renders the error as a problem document, titled with the status' default message
+func (e *StatusError) Problem() Problem {
+	title := defaultErrorMessages[e.Code]
+	if title == "" {
+		title = http.StatusText(e.Code)
+	}
+	return Problem{Type: "about:blank", Title: title, Status: e.Code, Detail: e.Msg}
+}
+
+// writes p as application/problem+json
+func WriteProblem(w http.ResponseWriter, p Problem) error {
+	if p.Status < 100 || p.Status > 999 {
+		p.Status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	return json.NewEncoder(w).Encode(p)
+}
+
+func acceptsProblemJSON(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(value, ",") {
+			mediaType, _, _ = strings.Cut(mediaType, ";")
+			if strings.EqualFold(strings.TrimSpace(mediaType), "application/problem+json") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reports err to the client, as problem+json if the client accepts it and the plain JSON
+// envelope otherwise. a *StatusError keeps its status and message, anything else is a 500
+// so internal details don't leak
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	se := StatusError{Code: http.StatusInternalServerError}
+	if target := (*StatusError)(nil); errors.As(err, &target) {
+		se = *target
+	}
+	if se.Code < 100 || se.Code > 999 {
+		// not a status net/http can send, it would panic in WriteHeader
+		se.Code = http.StatusInternalServerError
+	}
+
+	// the representation depends on Accept, so caches must key on it
+	SetVary(w, "Accept")
+	if acceptsProblemJSON(r) {
+		WriteProblem(w, se.Problem())
+		return
+	}
+	ErrorJSON(w, se.Msg, se.Code)
+}
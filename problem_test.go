@@ -0,0 +1,84 @@
+package apikit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func problemRequest() *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "application/json, application/problem+json;q=0.9")
+	return r
+}
+
+func TestWriteErrorProblem(t *testing.T) {
+	err := fmt.Errorf("loading order: %w", &StatusError{Code: http.StatusNotFound, Msg: "order 7 not found"})
+
+	rec := httptest.NewRecorder()
+	WriteError(rec, problemRequest(), err)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if rec.Header().Get("Vary") != "Accept" {
+		t.Fatalf("Vary = %q, want Accept", rec.Header().Get("Vary"))
+	}
+
+	var p Problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	want := Problem{Type: "about:blank", Title: "not found", Status: http.StatusNotFound, Detail: "order 7 not found"}
+	if p != want {
+		t.Fatalf("problem = %+v, want %+v", p, want)
+	}
+}
+
+func TestWriteErrorEnvelopeWithoutProblemAccept(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, httptest.NewRequest(http.MethodGet, "/", nil), &StatusError{Code: http.StatusConflict, Msg: "taken"})
+
+	if rec.Code != http.StatusConflict || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if env := decodeErrorEnvelope(t, rec); env.Error != "taken" {
+		t.Fatalf("envelope = %+v", env)
+	}
+}
+
+func TestWriteErrorHidesInternalErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, problemRequest(), errors.New("pq: connection refused"))
+
+	var p Problem
+	json.NewDecoder(rec.Body).Decode(&p)
+	if rec.Code != http.StatusInternalServerError || p.Detail != "" {
+		t.Fatalf("got %d %+v, want a 500 without details", rec.Code, p)
+	}
+}
+
+func TestWriteErrorNormalisesInvalidCode(t *testing.T) {
+	se := &StatusError{Code: 42, Msg: "odd"}
+
+	rec := httptest.NewRecorder()
+	WriteError(rec, problemRequest(), se)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if se.Code != 42 {
+		t.Fatal("caller's error was modified")
+	}
+
+	rec = httptest.NewRecorder()
+	WriteError(rec, httptest.NewRequest(http.MethodGet, "/", nil), &StatusError{Code: 0})
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("zero code: status = %d, want 500", rec.Code)
+	}
+}
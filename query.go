@@ -0,0 +1,100 @@
+package apikit
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// returns an error listing the allowed values unless value is one of them
+func OneOf(value string, allowed ...string) error {
+	if contains(allowed, value) {
+		return nil
+	}
+	return fmt.Errorf("invalid value %q, must be one of: %v", value, strings.Join(allowed, ", "))
+}
+
+// reads query param name, which must be one of allowed. returns "" and no error when absent
+func QueryEnum(r *http.Request, name string, allowed ...string) (string, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return "", nil
+	}
+
+	if err := OneOf(value, allowed...); err != nil {
+		return "", fmt.Errorf("%v: %w", name, err)
+	}
+	return value, nil
+}
+
+// accepts the lenient forms clients tend to send: 1/0, t/f, true/false, y/n, yes/no, on/off
+func parseLenientBool(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", s)
+}
+
+// reads a boolean query param, reporting whether it was present at all
+func QueryBool(r *http.Request, name string) (value bool, present bool, err error) {
+	if !r.URL.Query().Has(name) {
+		return false, false, nil
+	}
+
+	value, err = parseLenientBool(r.URL.Query().Get(name))
+	if err != nil {
+		return false, true, fmt.Errorf("%v: %w", name, err)
+	}
+	return value, true, nil
+}
+
+// like QueryBool but for headers, e.g. opt-in feature flags like X-Enable-Beta
+func HeaderBool(r *http.Request, name string) (value bool, present bool, err error) {
+	values := r.Header.Values(name)
+	if len(values) == 0 {
+		return false, false, nil
+	}
+
+	value, err = parseLenientBool(values[0])
+	if err != nil {
+		return false, true, fmt.Errorf("%v: %w", name, err)
+	}
+	return value, true, nil
+}
+
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// parses a sort spec like ?sort=name,-created, where a leading "-" sorts descending.
+// every field must be in allowedFields. returns nil when the param is absent
+func ParseSort(r *http.Request, param string, allowedFields []string) ([]SortField, error) {
+	fields := parseSortSpec(r.URL.Query().Get(param))
+	for _, field := range fields {
+		if !contains(allowedFields, field.Field) {
+			return nil, fmt.Errorf("%v: cannot sort by %q, must be one of: %v", param, field.Field, strings.Join(allowedFields, ", "))
+		}
+	}
+	return fields, nil
+}
+
+func parseSortSpec(spec string) []SortField {
+	if spec == "" {
+		return nil
+	}
+
+	var fields []SortField
+	for _, part := range strings.Split(spec, ",") {
+		field := SortField{Field: strings.TrimSpace(part)}
+		if strings.HasPrefix(field.Field, "-") {
+			field.Field = field.Field[1:]
+			field.Descending = true
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
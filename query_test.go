@@ -0,0 +1,78 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestQueryEnum(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?order=asc&bad=sideways", nil)
+
+	if got, err := QueryEnum(r, "order", "asc", "desc"); err != nil || got != "asc" {
+		t.Errorf("order: got %q, %v; want asc", got, err)
+	}
+	if got, err := QueryEnum(r, "missing", "asc", "desc"); err != nil || got != "" {
+		t.Errorf("missing: got %q, %v; want empty and no error", got, err)
+	}
+	if _, err := QueryEnum(r, "bad", "asc", "desc"); err == nil {
+		t.Error("bad: no error for a value outside the enum")
+	}
+}
+
+func TestQueryBool(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?a=YES&b=0&c=maybe&d=", nil)
+
+	for _, tc := range []struct {
+		name           string
+		value, present bool
+		wantErr        bool
+	}{
+		{"a", true, true, false},
+		{"b", false, true, false},
+		{"c", false, true, true},
+		{"d", false, true, true},
+		{"missing", false, false, false},
+	} {
+		value, present, err := QueryBool(r, tc.name)
+		if value != tc.value || present != tc.present || (err != nil) != tc.wantErr {
+			t.Errorf("%s: got %v, %v, %v", tc.name, value, present, err)
+		}
+	}
+}
+
+func TestHeaderBool(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Enable-Beta", " on ")
+
+	if value, present, err := HeaderBool(r, "X-Enable-Beta"); !value || !present || err != nil {
+		t.Errorf("X-Enable-Beta: got %v, %v, %v", value, present, err)
+	}
+	if _, present, err := HeaderBool(r, "X-Other"); present || err != nil {
+		t.Errorf("X-Other: got present %v, err %v", present, err)
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	allowed := []string{"name", "created"}
+
+	r := httptest.NewRequest(http.MethodGet, "/?sort=name,-created", nil)
+	got, err := ParseSort(r, "sort", allowed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SortField{{Field: "name"}, {Field: "created", Descending: true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseSort = %+v, want %+v", got, want)
+	}
+
+	if got, err := ParseSort(httptest.NewRequest(http.MethodGet, "/", nil), "sort", allowed); got != nil || err != nil {
+		t.Fatalf("absent: got %+v, %v; want nil, nil", got, err)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/?sort=-password", nil)
+	if _, err := ParseSort(r, "sort", allowed); err == nil {
+		t.Fatal("no error for a field outside the allowlist")
+	}
+}
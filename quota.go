@@ -0,0 +1,175 @@
+package apikit
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// backing store for request quotas, keyed by api key or token subject
+type QuotaStore interface {
+	// atomically records a request for key if it has quota left, returning the quota
+	// remaining after it. ok is false (and nothing is recorded) once the quota is exhausted
+	Increment(key string) (remaining int, ok bool, err error)
+	// returns the quota remaining for key, for inspection only
+	Remaining(key string) (int, error)
+}
+
+// optionally implemented by a QuotaStore that tracks quota windows. QuotaMiddleware then
+// also sets the X-RateLimit headers via SetRateLimitHeaders
+type QuotaWindowStore interface {
+	QuotaStore
+	// returns the key's quota and when it next resets
+	Window(key string) (limit int, reset time.Time, err error)
+}
+
+// derives the quota key for a request. an empty key skips the quota check
+type QuotaKeyFunc func(r *http.Request) string
+
+// uses the value of an api key header as the quota key
+func QuotaKeyFromHeader(header string) QuotaKeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(header)
+	}
+}
+
+// uses the authenticated subject (see SubjectFromContext) as the quota key.
+// the quota middleware must run after CookieTokenMiddleware or APIKeyAuth
+func QuotaKeyFromTokenSubject() QuotaKeyFunc {
+	return func(r *http.Request) string {
+		subject, ok := SubjectFromContext(r.Context())
+		assertPrerequisite(ok, "QuotaMiddleware keyed by token subject", "CookieTokenMiddleware")
+		return subject
+	}
+}
+
+// Middleware for enforcing request quotas. rejects with 429 once a key's quota is exhausted
+func QuotaMiddleware(store QuotaStore, keyFn QuotaKeyFunc, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := keyFn(r)
+		if key == "" {
+			next(w, r)
+			return
+		}
+
+		// a single atomic call decides, so concurrent requests can't all pass a separate check
+		remaining, ok, err := store.Increment(key)
+		if err != nil {
+			Error(w, "", http.StatusInternalServerError)
+			return
+		}
+
+		if !ok {
+			w.Header().Set("X-Quota-Remaining", "0")
+			setQuotaWindowHeaders(w, store, key, 0)
+			Error(w, "quota exhausted", http.StatusTooManyRequests)
+			return
+		}
+
+		w.Header().Set("X-Quota-Remaining", strconv.Itoa(remaining))
+		setQuotaWindowHeaders(w, store, key, remaining)
+		next(w, r)
+	}
+}
+
+func setQuotaWindowHeaders(w http.ResponseWriter, store QuotaStore, key string, remaining int) {
+	ws, ok := store.(QuotaWindowStore)
+	if !ok {
+		return
+	}
+
+	if limit, reset, err := ws.Window(key); err == nil {
+		SetRateLimitHeaders(w, limit, remaining, reset)
+	}
+}
+
+// in-memory QuotaStore with the same limit for every key. every key's count resets a period
+// after its first request in the window. implements QuotaWindowStore
+type MemoryQuotaStore struct {
+	mu     sync.Mutex
+	limit  int
+	period time.Duration
+	used   map[string]*quotaWindow
+	swept  time.Time
+	now    func() time.Time
+}
+
+type quotaWindow struct {
+	count int
+	reset time.Time
+}
+
+func NewMemoryQuotaStore(limit int, period time.Duration) *MemoryQuotaStore {
+	if period <= 0 {
+		panic("NewMemoryQuotaStore: period must be positive")
+	}
+	return &MemoryQuotaStore{limit: limit, period: period, used: make(map[string]*quotaWindow), now: time.Now}
+}
+
+func (s *MemoryQuotaStore) Increment(key string) (int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	win := s.window(key)
+	if win.count >= s.limit {
+		return 0, false, nil
+	}
+
+	win.count++
+	return s.limit - win.count, true, nil
+}
+
+func (s *MemoryQuotaStore) Remaining(key string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n := s.limit - s.window(key).count; n > 0 {
+		return n, nil
+	}
+	return 0, nil
+}
+
+func (s *MemoryQuotaStore) Window(key string) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.limit, s.window(key).reset, nil
+}
+
+// clears the counts of every key, e.g. at the start of a billing period
+func (s *MemoryQuotaStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.used = make(map[string]*quotaWindow)
+}
+
+// returns key's current window, starting a new one if it has expired.
+// must be called with mu held
+func (s *MemoryQuotaStore) window(key string) *quotaWindow {
+	now := s.now()
+
+	win, ok := s.used[key]
+	if !ok || !now.Before(win.reset) {
+		s.sweep(now)
+		win = &quotaWindow{reset: now.Add(s.period)}
+		s.used[key] = win
+	}
+	return win
+}
+
+// drops expired windows so keys seen once don't linger forever. runs at most once a period
+// so a stream of new keys doesn't rescan the map each time
+func (s *MemoryQuotaStore) sweep(now time.Time) {
+	if now.Sub(s.swept) < s.period {
+		return
+	}
+	s.swept = now
+
+	for k, w := range s.used {
+		if !now.Before(w.reset) {
+			delete(s.used, k)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestQuotaMiddleware(t *testing.T) {
+	store := NewMemoryQuotaStore(2, time.Hour)
+	h := QuotaMiddleware(store, QuotaKeyFromHeader("X-Key"), okHandler)
+
+	wantRemaining := []string{"1", "0"}
+	for i, want := range wantRemaining {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Key", "k")
+		rec := httptest.NewRecorder()
+		h(rec, r)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i, rec.Code)
+		}
+		if got := rec.Header().Get("X-Quota-Remaining"); got != want {
+			t.Fatalf("request %d: X-Quota-Remaining = %q, want %q", i, got, want)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Key", "k")
+	rec := httptest.NewRecorder()
+	h(rec, r)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", rec.Code)
+	}
+}
+
+func TestQuotaMiddlewareSkipsEmptyKey(t *testing.T) {
+	h := QuotaMiddleware(NewMemoryQuotaStore(0, time.Hour), QuotaKeyFromHeader("X-Key"), okHandler)
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+}
+
+func TestQuotaMiddlewareConcurrent(t *testing.T) {
+	const limit = 10
+	store := NewMemoryQuotaStore(limit, time.Hour)
+	h := QuotaMiddleware(store, QuotaKeyFromHeader("X-Key"), okHandler)
+
+	var mu sync.Mutex
+	passed := 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("X-Key", "k")
+			rec := httptest.NewRecorder()
+			h(rec, r)
+
+			if rec.Code == http.StatusOK {
+				mu.Lock()
+				passed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if passed != limit {
+		t.Fatalf("%d requests passed, want %d", passed, limit)
+	}
+}
+
+func TestMemoryQuotaStoreWindowResets(t *testing.T) {
+	now := time.Unix(1000, 0)
+	store := NewMemoryQuotaStore(1, time.Minute)
+	store.now = func() time.Time { return now }
+
+	if _, ok, _ := store.Increment("k"); !ok {
+		t.Fatal("first request rejected")
+	}
+	if _, ok, _ := store.Increment("k"); ok {
+		t.Fatal("second request in the window allowed")
+	}
+
+	limit, reset, _ := store.Window("k")
+	if limit != 1 || !reset.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Window = %d, %v", limit, reset)
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok, _ := store.Increment("k"); !ok {
+		t.Fatal("request after the window reset rejected")
+	}
+}
+
+func TestNewMemoryQuotaStorePanicsOnBadPeriod(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic for a zero period")
+		}
+	}()
+	NewMemoryQuotaStore(1, 0)
+}
+
+func TestQuotaKeyFromTokenSubject(t *testing.T) {
+	store := NewMemoryQuotaStore(1, time.Hour)
+	validate := func(key string) (string, bool) { return "user-" + key, true }
+	h := APIKeyAuth("", validate, QuotaMiddleware(store, QuotaKeyFromTokenSubject(), okHandler))
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(APIKeyHeader, "a")
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != want {
+			t.Fatalf("request %d: status = %d, want %d", i, rec.Code, want)
+		}
+	}
+
+	if n, _ := store.Remaining("user-a"); n != 0 {
+		t.Fatalf("Remaining(user-a) = %d, want 0", n)
+	}
+}
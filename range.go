@@ -0,0 +1,103 @@
+package apikit
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// both map to 416 Range Not Satisfiable
+var (
+	ErrInvalidRange        = errors.New("invalid range")
+	ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+)
+
+// a byte range of a resource
+type HttpRange struct {
+	Start  int64
+	Length int64
+}
+
+// the Content-Range header value for this range of a resource of size bytes
+func (hr HttpRange) ContentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", hr.Start, hr.Start+hr.Length-1, size)
+}
+
+// parses a Range header ("bytes=0-99,200-") for a resource of size bytes, like
+// http.ServeContent does. returns nil for an empty header
+func ParseRange(header string, size int64) ([]HttpRange, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	const prefix = "bytes="
+	if !strings.HasPrefix(header, prefix) {
+		return nil, ErrInvalidRange
+	}
+
+	var ranges []HttpRange
+	noOverlap := false
+	for _, spec := range strings.Split(header[len(prefix):], ",") {
+		spec = textproto.TrimString(spec)
+		if spec == "" {
+			continue
+		}
+
+		startStr, endStr, ok := strings.Cut(spec, "-")
+		if !ok {
+			return nil, ErrInvalidRange
+		}
+		startStr, endStr = textproto.TrimString(startStr), textproto.TrimString(endStr)
+
+		var r HttpRange
+		if startStr == "" {
+			// suffix range, the last n bytes
+			if endStr == "" || endStr[0] == '-' {
+				return nil, ErrInvalidRange
+			}
+			n, err := strconv.ParseInt(endStr, 10, 64)
+			if err != nil || n < 0 {
+				return nil, ErrInvalidRange
+			}
+			if n > size {
+				n = size
+			}
+			r.Start = size - n
+			r.Length = n
+		} else {
+			start, err := strconv.ParseInt(startStr, 10, 64)
+			if err != nil || start < 0 {
+				return nil, ErrInvalidRange
+			}
+			if start >= size {
+				noOverlap = true
+				continue
+			}
+
+			r.Start = start
+			if endStr == "" {
+				r.Length = size - start
+			} else {
+				end, err := strconv.ParseInt(endStr, 10, 64)
+				if err != nil || start > end {
+					return nil, ErrInvalidRange
+				}
+				if end >= size {
+					end = size - 1
+				}
+				r.Length = end - start + 1
+			}
+		}
+		ranges = append(ranges, r)
+	}
+
+	if noOverlap && len(ranges) == 0 {
+		return nil, ErrRangeNotSatisfiable
+	}
+	if len(ranges) == 0 {
+		return nil, ErrInvalidRange
+	}
+	return ranges, nil
+}
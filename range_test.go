@@ -0,0 +1,38 @@
+package apikit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	const size = 1000
+
+	for _, tc := range []struct {
+		header string
+		want   []HttpRange
+		err    error
+	}{
+		{"", nil, nil},
+		{"bytes=0-99", []HttpRange{{0, 100}}, nil},
+		{"bytes=900-", []HttpRange{{900, 100}}, nil},
+		{"bytes=-50", []HttpRange{{950, 50}}, nil},
+		{"bytes=-5000", []HttpRange{{0, 1000}}, nil},
+		{"bytes=0-9, 990-2000", []HttpRange{{0, 10}, {990, 10}}, nil},
+		{"bytes=1000-", nil, ErrRangeNotSatisfiable},
+		{"bytes=10-5", nil, ErrInvalidRange},
+		{"items=0-1", nil, ErrInvalidRange},
+		{"bytes=abc", nil, ErrInvalidRange},
+	} {
+		got, err := ParseRange(tc.header, size)
+		if err != tc.err || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: got %v, %v; want %v, %v", tc.header, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestContentRange(t *testing.T) {
+	if got := (HttpRange{Start: 10, Length: 5}).ContentRange(100); got != "bytes 10-14/100" {
+		t.Fatalf("ContentRange = %q", got)
+	}
+}
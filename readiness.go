@@ -0,0 +1,59 @@
+package apikit
+
+import (
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// tracks whether the server should be receiving traffic. the zero value is not ready
+type Readiness struct {
+	ready atomic.Bool
+}
+
+func (rd *Readiness) SetReady(ready bool) {
+	rd.ready.Store(ready)
+}
+
+func (rd *Readiness) IsReady() bool {
+	return rd.ready.Load()
+}
+
+// marks the server as not ready once one of sigs (SIGTERM by default) is received, waits
+// drain for load balancers to stop routing here, then calls shutdown (e.g. a func wrapping
+// http.Server.Shutdown). catching the signal disables Go's default exit, so shutdown is
+// what ends the process and must not be nil
+func (rd *Readiness) UnreadyOnSignal(drain time.Duration, shutdown func(), sigs ...os.Signal) {
+	if shutdown == nil {
+		panic("UnreadyOnSignal requires a shutdown func")
+	}
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGTERM}
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sigs...)
+
+	go func() {
+		<-c
+		rd.SetReady(false)
+		time.Sleep(drain)
+		signal.Stop(c)
+		shutdown()
+	}()
+}
+
+// Middleware for failing fast with 503 while not ready, so the load balancer retries elsewhere
+func (rd *Readiness) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !rd.IsReady() {
+			Error(w, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,53 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReadinessMiddleware(t *testing.T) {
+	var rd Readiness
+	h := rd.Middleware(okHandler)
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("not ready: status = %d, want 503", rec.Code)
+	}
+
+	rd.SetReady(true)
+	rec = httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ready: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestUnreadyOnSignal(t *testing.T) {
+	var rd Readiness
+	rd.SetReady(true)
+
+	shutdown := make(chan struct{})
+	rd.UnreadyOnSignal(10*time.Millisecond, func() { close(shutdown) }, syscall.SIGHUP)
+
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Skipf("cannot signal own process: %v", err)
+	}
+
+	select {
+	case <-shutdown:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown was not called after the signal")
+	}
+	if rd.IsReady() {
+		t.Fatal("still ready after the signal")
+	}
+}
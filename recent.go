@@ -0,0 +1,79 @@
+package apikit
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+type RequestRecord struct {
+	Method    string        `json:"method"`
+	Path      string        `json:"path"`
+	Status    int           `json:"status"`
+	Duration  time.Duration `json:"duration"`
+	RequestID string        `json:"requestId,omitempty"`
+	Time      time.Time     `json:"time"`
+}
+
+// a bounded ring of the most recent requests, for live debugging
+type RecentRequests struct {
+	mu      sync.Mutex
+	records []RequestRecord
+	next    int
+	full    bool
+}
+
+func NewRecentRequests(n int) *RecentRequests {
+	return &RecentRequests{records: make([]RequestRecord, n)}
+}
+
+func (rr *RecentRequests) add(rec RequestRecord) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if len(rr.records) == 0 {
+		return
+	}
+
+	rr.records[rr.next] = rec
+	rr.next = (rr.next + 1) % len(rr.records)
+	if rr.next == 0 {
+		rr.full = true
+	}
+}
+
+// the retained requests, oldest first
+func (rr *RecentRequests) Records() []RequestRecord {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	if !rr.full {
+		return append([]RequestRecord(nil), rr.records[:rr.next]...)
+	}
+	return append(append([]RequestRecord(nil), rr.records[rr.next:]...), rr.records[:rr.next]...)
+}
+
+// Middleware for recording requests into the ring. wrap it in RequestIDMiddleware to
+// record request IDs
+func (rr *RecentRequests) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		lrw := newLoggingResponseWriter(w)
+		next(lrw, r)
+
+		id, _ := RequestIDFromContext(r.Context())
+		rr.add(RequestRecord{
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Status:    lrw.statusCode,
+			Duration:  time.Since(start),
+			RequestID: id,
+			Time:      start,
+		})
+	}
+}
+
+// dumps the retained requests as JSON, oldest first. mount it on a debug-only route
+func (rr *RecentRequests) Handler(w http.ResponseWriter, r *http.Request) {
+	WriteJSON(w, rr.Records(), http.StatusOK)
+}
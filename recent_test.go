@@ -0,0 +1,39 @@
+package apikit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecentRequests(t *testing.T) {
+	rr := NewRecentRequests(2)
+	h := RequestIDMiddleware(rr.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	for _, path := range []string{"/a", "/b", "/missing"} {
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	records := rr.Records()
+	if len(records) != 2 {
+		t.Fatalf("%d records, want 2", len(records))
+	}
+	if records[0].Path != "/b" || records[1].Path != "/missing" {
+		t.Fatalf("records = %+v, want /b then /missing", records)
+	}
+	if records[1].Status != http.StatusNotFound || records[1].RequestID == "" {
+		t.Fatalf("last record = %+v, want 404 with a request ID", records[1])
+	}
+
+	rec := httptest.NewRecorder()
+	rr.Handler(rec, httptest.NewRequest(http.MethodGet, "/debug/requests", nil))
+	var dumped []RequestRecord
+	if err := json.NewDecoder(rec.Body).Decode(&dumped); err != nil || len(dumped) != 2 {
+		t.Fatalf("Handler dumped %d records, err %v", len(dumped), err)
+	}
+}
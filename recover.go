@@ -0,0 +1,99 @@
+package apikit
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// details of a recovered panic and the request it happened in
+type PanicInfo struct {
+	Value     any
+	Method    string
+	Path      string
+	RequestID string
+	Stack     []byte
+}
+
+func logPanic(r *http.Request, v any) PanicInfo {
+	info := PanicInfo{Value: v, Method: r.Method, Path: r.URL.String(), Stack: debug.Stack()}
+	info.RequestID, _ = RequestIDFromContext(r.Context())
+
+	if info.RequestID != "" {
+		log.Printf("PANIC: %v [%v] request_id=%v - %v\n%s", info.Method, info.Path, info.RequestID, v, info.Stack)
+	} else {
+		log.Printf("PANIC: %v [%v] - %v\n%s", info.Method, info.Path, v, info.Stack)
+	}
+	return info
+}
+
+// Middleware for recovering from handler panics, logging them with a stack trace and
+// responding 500. should be the outermost middleware, but inside RequestIDMiddleware
+// for the log to carry the request ID
+func RecoverMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return RecoverWithCallback(nil, next)
+}
+
+// like RecoverMiddleware but also hands each panic to onPanic, e.g. for error reporting
+func RecoverWithCallback(onPanic func(PanicInfo), next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v)
+				}
+
+				info := logPanic(r, v)
+				if onPanic != nil {
+					onPanic(info)
+				}
+				Error(w, "", http.StatusInternalServerError)
+			}
+		}()
+
+		next(w, r)
+	}
+}
+
+// runs next into a fresh buffer, reporting whether it panicked
+func serveBuffered(next http.HandlerFunc, r *http.Request) (bw *BufferedResponseWriter, panicked bool) {
+	bw = NewBufferedResponseWriter()
+
+	defer func() {
+		if v := recover(); v != nil {
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			logPanic(r, v)
+			panicked = true
+		}
+	}()
+
+	next(bw, r)
+	return bw, false
+}
+
+// Middleware for retrying GET and HEAD requests once when the handler panics or responds 5xx.
+// responses are buffered so nothing from the failed attempt reaches the client.
+// not suited to streaming handlers
+func RetryIdempotentMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		bw, panicked := serveBuffered(next, r)
+		if panicked || bw.StatusCode() >= 500 {
+			log.Printf("%v [%v] - retrying after failed attempt\n", r.Method, r.URL.String())
+			bw, panicked = serveBuffered(next, r)
+		}
+
+		if panicked {
+			Error(w, "", http.StatusInternalServerError)
+			return
+		}
+
+		bw.FlushTo(w)
+	}
+}
@@ -0,0 +1,112 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRetryIdempotentMiddleware(t *testing.T) {
+	captureLog(t)
+
+	calls := 0
+	h := RetryIdempotentMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Write([]byte("partial"))
+			panic("transient")
+		}
+		w.Write([]byte("ok"))
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if calls != 2 {
+		t.Fatalf("handler called %d times, want 2", calls)
+	}
+	if rec.Code != http.StatusOK || rec.Body.String() != "ok" {
+		t.Fatalf("got %d %q, want 200 \"ok\" with nothing from the failed attempt", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRetryIdempotentMiddlewareGivesUp(t *testing.T) {
+	captureLog(t)
+
+	calls := 0
+	h := RetryIdempotentMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		panic("persistent")
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if calls != 2 || rec.Code != http.StatusInternalServerError {
+		t.Fatalf("calls = %d, status = %d; want 2, 500", calls, rec.Code)
+	}
+}
+
+func TestRetryIdempotentMiddlewareSkipsPost(t *testing.T) {
+	calls := 0
+	h := RetryIdempotentMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	h(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+	if calls != 1 {
+		t.Fatalf("POST handled %d times, want 1", calls)
+	}
+}
+
+func TestRecoverWithCallback(t *testing.T) {
+	buf := captureLog(t)
+
+	var info PanicInfo
+	h := RequestIDMiddleware(RecoverWithCallback(func(pi PanicInfo) { info = pi }, func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/orders?id=7", nil)
+	r.Header.Set(RequestIDHeader, "req-42")
+	rec := httptest.NewRecorder()
+	h(rec, r)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if info.Value != "boom" || info.Method != http.MethodPost || info.Path != "/orders?id=7" || info.RequestID != "req-42" {
+		t.Fatalf("PanicInfo = %+v", info)
+	}
+	if len(info.Stack) == 0 {
+		t.Fatal("no stack captured")
+	}
+	if !strings.Contains(buf.String(), "request_id=req-42") {
+		t.Fatalf("log %q lacks the request ID", buf.String())
+	}
+}
+
+func TestRecoverMiddlewareWithoutRequestID(t *testing.T) {
+	buf := captureLog(t)
+
+	RecoverMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if strings.Contains(buf.String(), "request_id") {
+		t.Fatalf("log %q has an empty request_id", buf.String())
+	}
+}
+
+func TestRecoverMiddlewareRepanicsAbort(t *testing.T) {
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatalf("recovered %v, want http.ErrAbortHandler", v)
+		}
+	}()
+
+	RecoverMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	})(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
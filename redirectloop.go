@@ -0,0 +1,113 @@
+package apikit
+
+import (
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const redirectHopsCookie = "apikit_redirect_hops"
+
+// tracks redirect chains in a short-lived cookie as "<hops>.<base64 target path>"
+type redirectLoopWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	hops        int
+	target      string
+	wroteHeader bool
+}
+
+func (rw *redirectLoopWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.wroteHeader = true
+		rw.track(code)
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *redirectLoopWriter) Write(p []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	return rw.ResponseWriter.Write(p)
+}
+
+func (rw *redirectLoopWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+func (rw *redirectLoopWriter) track(code int) {
+	if code < 300 || code >= 400 {
+		if rw.target != "" { // the chain ended
+			deleteRedirectHops(rw)
+		}
+		return
+	}
+
+	loc, err := rw.r.URL.Parse(rw.Header().Get("Location"))
+	if err != nil {
+		return
+	}
+
+	hops := 1
+	if rw.r.URL.Path == rw.target { // this request continues the chain
+		hops = rw.hops + 1
+	}
+
+	http.SetCookie(rw, &http.Cookie{
+		Name:     redirectHopsCookie,
+		Value:    strconv.Itoa(hops) + "." + base64.RawURLEncoding.EncodeToString([]byte(loc.Path)),
+		Path:     "/", // the chain crosses paths, a default path would hide it from the next hop
+		HttpOnly: true,
+		MaxAge:   10,
+	})
+}
+
+// deletes the hops cookie, matching the Path it was set with
+func deleteRedirectHops(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:   redirectHopsCookie,
+		Value:  "",
+		Path:   "/",
+		MaxAge: -1,
+	})
+}
+
+func readRedirectHops(r *http.Request) (int, string) {
+	cookie, err := r.Cookie(redirectHopsCookie)
+	if err != nil {
+		return 0, ""
+	}
+
+	hopsStr, target, _ := strings.Cut(cookie.Value, ".")
+	hops, err := strconv.Atoi(hopsStr)
+	if err != nil {
+		return 0, ""
+	}
+
+	path, err := base64.RawURLEncoding.DecodeString(target)
+	if err != nil {
+		return 0, ""
+	}
+	return hops, string(path)
+}
+
+// Middleware for breaking redirect loops. clients following more than maxHops consecutive
+// redirects get a 508 Loop Detected instead of another redirect. protects against
+// misconfigured redirecting middleware; clients must keep cookies for it to work
+func RedirectLoopGuard(maxHops int, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hops, target := readRedirectHops(r)
+
+		if target != "" && target == r.URL.Path && hops >= maxHops {
+			log.Printf("WARNING: %v [%v] - redirect loop detected after %v hops\n", r.Method, r.URL.String(), hops)
+			deleteRedirectHops(w)
+			Error(w, "", http.StatusLoopDetected)
+			return
+		}
+
+		next(&redirectLoopWriter{ResponseWriter: w, r: r, hops: hops, target: target}, r)
+	}
+}
@@ -0,0 +1,95 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// a client that carries cookies across redirects, like a browser
+func cookieClient() *http.Client {
+	jar, _ := cookiejar.New(nil)
+	return &http.Client{Jar: jar}
+}
+
+// the client's cookies called name that would be sent to rawURL
+func cookiesNamed(client *http.Client, rawURL, name string) []*http.Cookie {
+	u, _ := url.Parse(rawURL)
+	var named []*http.Cookie
+	for _, c := range client.Jar.Cookies(u) {
+		if c.Name == name {
+			named = append(named, c)
+		}
+	}
+	return named
+}
+
+func TestRedirectLoopGuard(t *testing.T) {
+	buf := captureLog(t)
+
+	// /a/loop and /b/loop redirect to each other. the cookie must cross both paths
+	hits := 0
+	srv := httptest.NewServer(RedirectLoopGuard(3, func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch r.URL.Path {
+		case "/a/loop":
+			http.Redirect(w, r, "/b/loop", http.StatusFound)
+		case "/b/loop":
+			http.Redirect(w, r, "/a/loop", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer srv.Close()
+
+	client := cookieClient()
+	resp, err := client.Get(srv.URL + "/a/loop")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusLoopDetected {
+		t.Fatalf("status = %d, want 508", resp.StatusCode)
+	}
+	if hits != 3 {
+		t.Fatalf("handler ran %d times, want the loop broken after 3 hops", hits)
+	}
+	if !strings.Contains(buf.String(), "redirect loop detected") {
+		t.Fatalf("loop not logged, got %q", buf.String())
+	}
+	if cookies := cookiesNamed(client, srv.URL, redirectHopsCookie); len(cookies) != 0 {
+		t.Fatalf("hops cookie left behind: %v", cookies)
+	}
+}
+
+func TestRedirectLoopGuardFiniteChain(t *testing.T) {
+	srv := httptest.NewServer(RedirectLoopGuard(3, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old/a":
+			http.Redirect(w, r, "/new/b", http.StatusMovedPermanently)
+		case "/new/b":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer srv.Close()
+
+	client := cookieClient()
+	resp, err := client.Get(srv.URL + "/old/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200 at the end of the chain", resp.StatusCode)
+	}
+	if cookies := cookiesNamed(client, srv.URL, redirectHopsCookie); len(cookies) != 0 {
+		t.Fatalf("hops cookie not cleared when the chain ended: %v", cookies)
+	}
+}
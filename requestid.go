@@ -0,0 +1,31 @@
+package apikit
+
+import (
+	"context"
+	"net/http"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// Middleware for tagging each request with an ID, taken from X-Request-ID when the client
+// (or a proxy) sent one and generated otherwise. the ID is echoed in the response header
+func RequestIDMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			var err error
+			if id, err = newNonce(); err != nil {
+				Error(w, "", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+		next(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	}
+}
+
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok
+}
@@ -0,0 +1,87 @@
+package apikit
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// backoff between retries: Initial, then multiplied by Multiplier each retry, capped at Max
+type RetryConfig struct {
+	Initial    time.Duration
+	Multiplier float64
+	// no cap when zero
+	Max time.Duration
+	// randomizes each delay by up to ±Jitter of itself (0-1)
+	Jitter float64
+}
+
+// the backoff used by RetryArgs0-3: 1s, doubling each retry
+var DefaultRetryConfig = RetryConfig{Initial: time.Second, Multiplier: 2}
+
+// the delays the retry loop waits before each of the first attempts retries,
+// after clamping to Max and before jitter
+func BackoffSchedule(cfg RetryConfig, attempts int) []time.Duration {
+	multiplier := cfg.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	if attempts < 0 {
+		attempts = 0
+	}
+
+	schedule := make([]time.Duration, 0, attempts)
+	delay := float64(cfg.Initial)
+	for i := 0; i < attempts; i++ {
+		d := time.Duration(delay)
+		if cfg.Max > 0 && delay >= float64(cfg.Max) {
+			d = cfg.Max
+		}
+		schedule = append(schedule, d)
+
+		// stop growing once capped so delay can't overflow
+		if cfg.Max <= 0 || delay < float64(cfg.Max) {
+			delay *= multiplier
+		}
+	}
+	return schedule
+}
+
+func applyJitter(d time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return d
+	}
+	if jitter > 1 {
+		jitter = 1
+	}
+	return time.Duration(float64(d) * (1 + jitter*(2*rand.Float64()-1)))
+}
+
+// like RetryArgs0 but waits according to cfg between tries. fn is always called at least
+// once, even when nTries < 1
+func RetryWithConfig[T any](cfg RetryConfig, nTries int, fn func() (T, error)) (T, error) {
+	if nTries < 1 {
+		nTries = 1
+	}
+	schedule := BackoffSchedule(cfg, nTries-1)
+
+	var returnedT T
+	var returnedError error
+
+	for try := 0; try < nTries; try++ {
+		if try > 0 {
+			fmt.Printf("ERROR: %v retrying....\n", returnedError)
+			time.Sleep(applyJitter(schedule[try-1], cfg.Jitter))
+		}
+
+		returnedT, returnedError = fn()
+
+		if returnedError != nil { // error was returned, retry
+			continue
+		}
+
+		return returnedT, nil
+	}
+	return returnedT, returnedError
+}
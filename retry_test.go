@@ -0,0 +1,106 @@
+package apikit
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBackoffSchedule(t *testing.T) {
+	cfg := RetryConfig{Initial: time.Second, Multiplier: 2, Max: 5 * time.Second}
+
+	got := BackoffSchedule(cfg, 5)
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("schedule = %v, want %v", got, want)
+	}
+
+	if got := BackoffSchedule(cfg, -1); len(got) != 0 {
+		t.Fatalf("schedule for negative attempts = %v, want empty", got)
+	}
+
+	// a multiplier below 1 would shrink the delay, so it is treated as constant
+	got = BackoffSchedule(RetryConfig{Initial: time.Second, Multiplier: 0.5}, 3)
+	want = []time.Duration{time.Second, time.Second, time.Second}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("schedule with multiplier < 1 = %v, want %v", got, want)
+	}
+}
+
+func TestApplyJitterStaysInBounds(t *testing.T) {
+	d := 100 * time.Millisecond
+	if got := applyJitter(d, 0); got != d {
+		t.Fatalf("no jitter = %v, want %v", got, d)
+	}
+
+	for i := 0; i < 1000; i++ {
+		if got := applyJitter(d, 0.25); got < 75*time.Millisecond || got > 125*time.Millisecond {
+			t.Fatalf("jitter 0.25 gave %v, want within ±25%% of %v", got, d)
+		}
+		// jitter above 1 is capped, so the delay never goes negative
+		if got := applyJitter(d, 3); got < 0 || got > 2*d {
+			t.Fatalf("jitter 3 gave %v, want within [0, %v]", got, 2*d)
+		}
+	}
+}
+
+func TestRetryWithConfig(t *testing.T) {
+	cfg := RetryConfig{Initial: time.Millisecond, Multiplier: 2}
+
+	calls := 0
+	got, err := RetryWithConfig(cfg, 3, func() (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, errors.New("not yet")
+		}
+		return 42, nil
+	})
+	if err != nil || got != 42 || calls != 3 {
+		t.Fatalf("got %v, %v after %d calls, want 42 after 3", got, err, calls)
+	}
+
+	calls = 0
+	errFail := errors.New("always")
+	_, err = RetryWithConfig(cfg, 2, func() (int, error) {
+		calls++
+		return 0, errFail
+	})
+	if !errors.Is(err, errFail) || calls != 2 {
+		t.Fatalf("err = %v after %d calls, want the last error after 2", err, calls)
+	}
+}
+
+func TestRetryWithConfigCallsAtLeastOnce(t *testing.T) {
+	for _, nTries := range []int{0, -3} {
+		calls := 0
+		got, err := RetryWithConfig(RetryConfig{Initial: time.Millisecond}, nTries, func() (string, error) {
+			calls++
+			return "ok", nil
+		})
+		if err != nil || got != "ok" || calls != 1 {
+			t.Fatalf("nTries %d: got %q, %v after %d calls, want one call", nTries, got, err, calls)
+		}
+	}
+}
+
+func TestRetryReflective(t *testing.T) {
+	add := func(a, b int) (int, error) { return a + b, nil }
+	got, err := Retry[int](3, add, 2, 3)
+	if err != nil || got != 5 {
+		t.Fatalf("Retry(add) = %v, %v, want 5", got, err)
+	}
+
+	errFail := errors.New("boom")
+	fail := func(s string) (string, error) { return "", errFail }
+	if _, err := Retry[string](1, fail, "x"); !errors.Is(err, errFail) {
+		t.Fatalf("Retry(fail) err = %v, want %v", err, errFail)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Retry with a non-(T, error) fn did not panic")
+		}
+	}()
+	Retry[int](1, func() int { return 1 })
+}
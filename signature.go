@@ -0,0 +1,69 @@
+package apikit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Signature"
+	TimestampHeader = "X-Timestamp"
+)
+
+func hmacSHA256(secret, data []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// the hex HMAC-SHA256 signature of a request, computed over "<unix timestamp>.<body>"
+func SignRequestPayload(secret []byte, timestamp time.Time, body []byte) string {
+	return hex.EncodeToString(signRequestPayload(secret, timestamp, body))
+}
+
+func signRequestPayload(secret []byte, timestamp time.Time, body []byte) []byte {
+	payload := append([]byte(strconv.FormatInt(timestamp.Unix(), 10)+"."), body...)
+	return hmacSHA256(secret, payload)
+}
+
+// Middleware for verifying HMAC signed requests. X-Timestamp (unix seconds) must be within
+// skew of the server time and X-Signature must match SignRequestPayload, so captured requests
+// can't be replayed later. bodies over maxBytes are rejected with 413 before any hashing
+func VerifySignature(secret []byte, skew time.Duration, maxBytes int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		unix, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		if err != nil {
+			Error(w, "missing or invalid timestamp", http.StatusUnauthorized)
+			return
+		}
+
+		timestamp := time.Unix(unix, 0)
+		if age := time.Since(timestamp); age > skew || age < -skew {
+			Error(w, "stale timestamp", http.StatusUnauthorized)
+			return
+		}
+
+		sig, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+		if err != nil || len(sig) == 0 {
+			Error(w, "missing or invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := readBody(r, maxBytes)
+		if err != nil {
+			writeReadBodyError(w, err)
+			return
+		}
+
+		if !hmac.Equal(sig, signRequestPayload(secret, timestamp, body)) {
+			Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,49 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSignedRequest(secret []byte, ts time.Time, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set(TimestampHeader, strconv.FormatInt(ts.Unix(), 10))
+	r.Header.Set(SignatureHeader, SignRequestPayload(secret, ts, []byte(body)))
+	return r
+}
+
+func TestVerifySignature(t *testing.T) {
+	secret := []byte("s3cret")
+	h := VerifySignature(secret, time.Minute, 64, okHandler)
+	now := time.Now()
+
+	for _, tc := range []struct {
+		name string
+		r    *http.Request
+		want int
+	}{
+		{"valid", newSignedRequest(secret, now, `{"a":1}`), http.StatusOK},
+		{"wrong secret", newSignedRequest([]byte("other"), now, `{"a":1}`), http.StatusUnauthorized},
+		{"stale", newSignedRequest(secret, now.Add(-time.Hour), `{"a":1}`), http.StatusUnauthorized},
+		{"too large", newSignedRequest(secret, now, strings.Repeat("x", 65)), http.StatusRequestEntityTooLarge},
+	} {
+		rec := httptest.NewRecorder()
+		h(rec, tc.r)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.name, rec.Code, tc.want)
+		}
+	}
+
+	// a body tampered with after signing
+	r := newSignedRequest(secret, now, `{"a":1}`)
+	r.Body = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":2}`)).Body
+	rec := httptest.NewRecorder()
+	h(rec, r)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("tampered: status = %d, want 401", rec.Code)
+	}
+}
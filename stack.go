@@ -0,0 +1,117 @@
+package apikit
+
+import (
+	"net/http"
+)
+
+// toggles for the standard middleware assembled by BuildStack. zero values disable
+type StackConfig struct {
+	RequestID bool
+	Recover   bool
+	Sequence  bool
+	Log       bool
+	// paths left out of the request log, e.g. health checks
+	LogSkipPaths []string
+	// log requests with headers larger than this many bytes
+	HeaderSizeThreshold int
+	Gzip                bool
+	Readiness           *Readiness
+	CollapseSlashes     bool
+	// redirect GET/HEAD requests to the collapsed path rather than rewriting
+	RedirectSlashes bool
+	AllowedIPRanges []string
+	// proxies trusted when resolving the client IP for AllowedIPRanges
+	TrustedProxies TrustedProxies
+	// authentication, e.g. a CookieTokenMiddleware or APIKeyAuth closure. runs before quota
+	// so quotas can be keyed by subject
+	Auth       Middleware
+	QuotaStore QuotaStore
+	QuotaKey   QuotaKeyFunc
+	// key quotas by authenticated subject instead of QuotaKey. requires Auth
+	QuotaBySubject bool
+}
+
+// assembles the middleware enabled in cfg, outermost first, in the order they need to run:
+// request ID (outermost, so recover can log it), recover, sequence, log, header size, gzip,
+// readiness, slash collapsing, IP allowlist, auth, quota.
+// panics on combinations that can't work, like QuotaBySubject without Auth
+func BuildStack(cfg StackConfig) Middleware {
+	if cfg.QuotaBySubject {
+		if cfg.Auth == nil {
+			panic("BuildStack: QuotaBySubject requires Auth")
+		}
+		cfg.QuotaKey = QuotaKeyFromTokenSubject()
+	}
+
+	layers := stackLayers(cfg)
+	stack := make([]Middleware, len(layers))
+	for i, layer := range layers {
+		stack[i] = layer.mw
+	}
+	return Chain(stack...)
+}
+
+// a middleware enabled by StackConfig, named so the assembled order can be inspected
+type stackLayer struct {
+	name string
+	mw   Middleware
+}
+
+// the layers enabled in cfg, outermost first
+func stackLayers(cfg StackConfig) []stackLayer {
+	var stack []stackLayer
+
+	if cfg.RequestID {
+		stack = append(stack, stackLayer{"request_id", RequestIDMiddleware})
+	}
+	if cfg.Recover {
+		stack = append(stack, stackLayer{"recover", RecoverMiddleware})
+	}
+	if cfg.Sequence {
+		stack = append(stack, stackLayer{"sequence", SequenceMiddleware})
+	}
+	if cfg.Log {
+		stack = append(stack, stackLayer{"log", LogMiddlewareExcept(cfg.LogSkipPaths...)})
+	}
+	if cfg.HeaderSizeThreshold > 0 {
+		stack = append(stack, stackLayer{"header_size", func(next http.HandlerFunc) http.HandlerFunc {
+			return HeaderSizeMiddleware(cfg.HeaderSizeThreshold, next)
+		}})
+	}
+	if cfg.Gzip {
+		stack = append(stack, stackLayer{"gzip", GzipMiddleware})
+	}
+	if cfg.Readiness != nil {
+		stack = append(stack, stackLayer{"readiness", cfg.Readiness.Middleware})
+	}
+	if cfg.CollapseSlashes {
+		stack = append(stack, stackLayer{"collapse_slashes", func(next http.HandlerFunc) http.HandlerFunc {
+			return CollapseSlashesMiddleware(cfg.RedirectSlashes, next)
+		}})
+	}
+	if len(cfg.AllowedIPRanges) > 0 {
+		stack = append(stack, stackLayer{"ip_allowlist", func(next http.HandlerFunc) http.HandlerFunc {
+			return AllowIPRanges(cfg.AllowedIPRanges, cfg.TrustedProxies, next)
+		}})
+	}
+	if cfg.Auth != nil {
+		stack = append(stack, stackLayer{"auth", cfg.Auth})
+	}
+	if cfg.QuotaStore != nil && cfg.QuotaKey != nil {
+		stack = append(stack, stackLayer{"quota", func(next http.HandlerFunc) http.HandlerFunc {
+			return QuotaMiddleware(cfg.QuotaStore, cfg.QuotaKey, next)
+		}})
+	}
+
+	return stack
+}
+
+// composes middleware so the first one given is the outermost
+func Chain(middleware ...Middleware) Middleware {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		for i := len(middleware) - 1; i >= 0; i-- {
+			next = middleware[i](next)
+		}
+		return next
+	}
+}
@@ -0,0 +1,152 @@
+package apikit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChainOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next(w, r)
+			}
+		}
+	}
+
+	Chain(mark("a"), mark("b"), mark("c"))(okHandler)(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(order, want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+}
+
+func TestBuildStackLayerOrder(t *testing.T) {
+	auth := func(next http.HandlerFunc) http.HandlerFunc { return next }
+	cfg := StackConfig{
+		RequestID:           true,
+		Recover:             true,
+		Sequence:            true,
+		Log:                 true,
+		HeaderSizeThreshold: 8 << 10,
+		Gzip:                true,
+		Readiness:           &Readiness{},
+		CollapseSlashes:     true,
+		AllowedIPRanges:     []string{"10.0.0.0/8"},
+		Auth:                auth,
+		QuotaStore:          NewMemoryQuotaStore(10, time.Hour),
+		QuotaKey:            QuotaKeyFromHeader("X-Key"),
+	}
+
+	var names []string
+	for _, layer := range stackLayers(cfg) {
+		names = append(names, layer.name)
+	}
+	want := []string{
+		"request_id", "recover", "sequence", "log", "header_size", "gzip",
+		"readiness", "collapse_slashes", "ip_allowlist", "auth", "quota",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("layers = %v, want %v", names, want)
+	}
+}
+
+func TestBuildStackZeroConfig(t *testing.T) {
+	if layers := stackLayers(StackConfig{}); len(layers) != 0 {
+		t.Fatalf("zero config enabled %d layers", len(layers))
+	}
+
+	// a quota store without a key func has nothing to key by, so is left out too
+	if layers := stackLayers(StackConfig{QuotaStore: NewMemoryQuotaStore(1, time.Hour)}); len(layers) != 0 {
+		t.Fatalf("QuotaStore without QuotaKey enabled %d layers", len(layers))
+	}
+
+	buf := captureLog(t)
+	var got *http.Request
+	r := httptest.NewRequest(http.MethodGet, "//a//b", nil)
+	rec := httptest.NewRecorder()
+	BuildStack(StackConfig{})(func(w http.ResponseWriter, r *http.Request) { got = r })(rec, r)
+
+	if got != r {
+		t.Fatal("zero config handed the handler a different request")
+	}
+	if len(rec.Header()) != 0 || buf.Len() != 0 {
+		t.Fatalf("zero config touched the response: headers %v, log %q", rec.Header(), buf.String())
+	}
+}
+
+func TestBuildStackIPAllowlistBeforeAuth(t *testing.T) {
+	authed := false
+	auth := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			authed = true
+			next(w, r)
+		}
+	}
+
+	h := BuildStack(StackConfig{AllowedIPRanges: []string{"10.0.0.0/8"}, Auth: auth})(okHandler)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+	h(rec, r)
+
+	if rec.Code != http.StatusForbidden || authed {
+		t.Fatalf("status = %d, authed = %v; want 403 before auth runs", rec.Code, authed)
+	}
+}
+
+func TestBuildStackQuotaBySubjectRequiresAuth(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("no panic for QuotaBySubject without Auth")
+		}
+	}()
+	BuildStack(StackConfig{QuotaStore: NewMemoryQuotaStore(1, time.Hour), QuotaBySubject: true})
+}
+
+func TestBuildStackQuotaBySubject(t *testing.T) {
+	store := NewMemoryQuotaStore(1, time.Hour)
+	auth := func(next http.HandlerFunc) http.HandlerFunc {
+		return APIKeyAuth("", func(key string) (string, bool) { return "svc-" + key, true }, next)
+	}
+
+	h := BuildStack(StackConfig{Auth: auth, QuotaStore: store, QuotaBySubject: true})(okHandler)
+
+	for i, want := range []int{http.StatusOK, http.StatusTooManyRequests} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(APIKeyHeader, "k")
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		if rec.Code != want {
+			t.Fatalf("request %d: status = %d, want %d", i, rec.Code, want)
+		}
+	}
+	if n, _ := store.Remaining("svc-k"); n != 0 {
+		t.Fatalf("quota not keyed by subject, Remaining(svc-k) = %d", n)
+	}
+}
+
+func TestBuildStackRecoverSeesRequestID(t *testing.T) {
+	buf := captureLog(t)
+
+	h := BuildStack(StackConfig{RequestID: true, Recover: true})(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	h(rec, r)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+	if !strings.Contains(buf.String(), "req-1") {
+		t.Fatalf("panic log %q lacks the request ID", buf.String())
+	}
+}
@@ -0,0 +1,102 @@
+package apikit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	ErrStreamingUnsupported = errors.New("response writer does not support flushing")
+	ErrClientDisconnected   = errors.New("client disconnected")
+)
+
+// returns ErrClientDisconnected once the request context is done
+func checkConnected(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ErrClientDisconnected
+	default:
+		return nil
+	}
+}
+
+// writes server-sent events, flushing after each one.
+// writes fail with ErrClientDisconnected as soon as the request context is done
+type SSEWriter struct {
+	w   http.ResponseWriter
+	f   http.Flusher
+	ctx context.Context
+}
+
+func NewSSEWriter(w http.ResponseWriter, r *http.Request) (*SSEWriter, error) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrStreamingUnsupported
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+
+	return &SSEWriter{w, f, r.Context()}, nil
+}
+
+// sends a single event. event may be empty for the default "message" event
+func (s *SSEWriter) Send(event string, data []byte) error {
+	if err := checkConnected(s.ctx); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event)
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		fmt.Fprintf(&buf, "data: %s\n", line)
+	}
+	buf.WriteByte('\n')
+
+	if _, err := s.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	s.f.Flush()
+	return nil
+}
+
+// writes newline delimited JSON, flushing after each value.
+// writes fail with ErrClientDisconnected as soon as the request context is done
+type NDJSONWriter struct {
+	enc *json.Encoder
+	f   http.Flusher
+	ctx context.Context
+}
+
+func NewNDJSONWriter(w http.ResponseWriter, r *http.Request) (*NDJSONWriter, error) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrStreamingUnsupported
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+
+	return &NDJSONWriter{json.NewEncoder(w), f, r.Context()}, nil
+}
+
+func (n *NDJSONWriter) Encode(v any) error {
+	if err := checkConnected(n.ctx); err != nil {
+		return err
+	}
+
+	if err := n.enc.Encode(v); err != nil {
+		return err
+	}
+	n.f.Flush()
+	return nil
+}
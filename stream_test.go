@@ -0,0 +1,63 @@
+package apikit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSSEWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sse, err := NewSSEWriter(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sse.Send("tick", []byte("a\nb")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", got)
+	}
+	if got, want := rec.Body.String(), "event: tick\ndata: a\ndata: b\n\n"; got != want {
+		t.Fatalf("body = %q, want %q", got, want)
+	}
+	if !rec.Flushed {
+		t.Fatal("event was not flushed")
+	}
+}
+
+func TestStreamWritersStopAfterDisconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+
+	sse, err := NewSSEWriter(httptest.NewRecorder(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nd, err := NewNDJSONWriter(httptest.NewRecorder(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if err := sse.Send("", []byte("x")); err != ErrClientDisconnected {
+		t.Fatalf("SSEWriter.Send err = %v, want ErrClientDisconnected", err)
+	}
+	if err := nd.Encode(1); err != ErrClientDisconnected {
+		t.Fatalf("NDJSONWriter.Encode err = %v, want ErrClientDisconnected", err)
+	}
+}
+
+type noFlushWriter struct {
+	http.ResponseWriter
+}
+
+func TestNewSSEWriterUnsupported(t *testing.T) {
+	_, err := NewSSEWriter(noFlushWriter{httptest.NewRecorder()}, httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != ErrStreamingUnsupported {
+		t.Fatalf("err = %v, want ErrStreamingUnsupported", err)
+	}
+}
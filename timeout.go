@@ -0,0 +1,197 @@
+package apikit
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// records whether a body read is blocked right now
+type trackedBody struct {
+	io.ReadCloser
+	reading atomic.Int32
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	b.reading.Add(1)
+	defer b.reading.Add(-1)
+	return b.ReadCloser.Read(p)
+}
+
+// Middleware for bounding the total time spent on a request, reading the body included.
+// responds 408 when the budget runs out while the handler is blocked reading the body, 503
+// otherwise, including when the handler never reads the body. the handler's response is buffered, so this doesn't suit streaming
+func RequestBudgetMiddleware(budget time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deadline := time.Now().Add(budget)
+
+		ctx, cancel := context.WithDeadline(r.Context(), deadline)
+		defer cancel()
+
+		// unblocks body reads stuck on a slow client, where the server supports it
+		http.NewResponseController(w).SetReadDeadline(deadline)
+
+		body := &trackedBody{ReadCloser: r.Body}
+		if r.Body != nil {
+			r.Body = body
+		}
+
+		bw := NewBufferedResponseWriter()
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+
+		go func() {
+			defer func() {
+				if v := recover(); v != nil {
+					panicked <- v
+				}
+			}()
+			next(bw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			bw.FlushTo(w)
+		case v := <-panicked:
+			panic(v)
+		case <-ctx.Done():
+			if body.reading.Load() > 0 {
+				Error(w, "request timeout", http.StatusRequestTimeout)
+				return
+			}
+			Error(w, "", http.StatusServiceUnavailable)
+		}
+	}
+}
+
+// guards the real writer so a timed out handler can't write to it any more
+type timeoutWriter struct {
+	w           http.ResponseWriter
+	ctx         context.Context
+	header      http.Header
+	mu          sync.Mutex
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// whether the deadline has passed, even if the middleware hasn't noticed yet. a write racing
+// the deadline must not count as the start of the response. must be called with mu held
+func (tw *timeoutWriter) expiredLocked() bool {
+	return tw.timedOut || tw.ctx.Err() != nil
+}
+
+// must be called with mu held
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	if tw.wroteHeader {
+		return
+	}
+	for k, vs := range tw.header {
+		tw.w.Header()[k] = vs
+	}
+	tw.w.WriteHeader(code)
+	tw.wroteHeader = true
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if !tw.expiredLocked() {
+		tw.writeHeaderLocked(code)
+	}
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.expiredLocked() {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	return tw.w.Write(p)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.expiredLocked() {
+		return
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Middleware for timing out handlers that may stream. output reaches the client as it is
+// written. if the deadline hits before anything was written the client gets a 503, otherwise
+// the status can no longer change, so the connection is terminated instead
+func StreamingTimeoutMiddleware(timeout time.Duration, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		tw := &timeoutWriter{w: w, ctx: ctx, header: make(http.Header)}
+		done := make(chan struct{})
+		panicked := make(chan any, 1)
+
+		go func() {
+			defer func() {
+				if v := recover(); v != nil {
+					panicked <- v
+				}
+			}()
+			next(tw, r.WithContext(ctx))
+			close(done)
+		}()
+
+		select {
+		case <-done:
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			// the handler may have only set headers, or written nothing at all
+			if !tw.wroteHeader {
+				if tw.expiredLocked() {
+					Error(w, "", http.StatusServiceUnavailable)
+				} else {
+					tw.writeHeaderLocked(http.StatusOK)
+				}
+			}
+			return
+		case v := <-panicked:
+			panic(v)
+		case <-ctx.Done():
+		}
+
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		tw.timedOut = true
+
+		if !tw.wroteHeader {
+			Error(w, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		log.Printf("%v [%v] - timed out mid-response, terminating connection\n", r.Method, r.URL.String())
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		// no hijacking (e.g. HTTP/2), have the server abort the response instead
+		panic(http.ErrAbortHandler)
+	}
+}
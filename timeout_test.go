@@ -0,0 +1,116 @@
+package apikit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequestBudgetMiddleware(t *testing.T) {
+	h := RequestBudgetMiddleware(time.Second, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "done" {
+		t.Fatalf("got %d %q, want 200 done", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRequestBudgetSlowHandler(t *testing.T) {
+	// the body is never read, so the handler is to blame
+	h := RequestBudgetMiddleware(20*time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/", pr))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+}
+
+func TestRequestBudgetSlowBody(t *testing.T) {
+	h := RequestBudgetMiddleware(20*time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+	})
+
+	// a client that never finishes sending
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodPost, "/", pr))
+	if rec.Code != http.StatusRequestTimeout {
+		t.Fatalf("status = %d, want 408", rec.Code)
+	}
+}
+
+func TestStreamingTimeoutBeforeWrite(t *testing.T) {
+	h := StreamingTimeoutMiddleware(20*time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Write([]byte("too late"))
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503", rec.Code)
+	}
+}
+
+func TestStreamingTimeoutMidResponse(t *testing.T) {
+	captureLog(t)
+
+	wrote := make(chan struct{})
+	h := StreamingTimeoutMiddleware(20*time.Millisecond, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		close(wrote)
+		<-r.Context().Done()
+	})
+
+	rec := httptest.NewRecorder()
+	defer func() {
+		// a recorder can't be hijacked, so the response is aborted instead
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Fatalf("recovered %v, want http.ErrAbortHandler", v)
+		}
+		<-wrote
+		if rec.Code != http.StatusOK || rec.Body.String() != "partial" {
+			t.Fatalf("got %d %q, want the partial response", rec.Code, rec.Body.String())
+		}
+	}()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestStreamingTimeoutInTime(t *testing.T) {
+	h := StreamingTimeoutMiddleware(time.Second, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("ok"))
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusAccepted || rec.Body.String() != "ok" {
+		t.Fatalf("got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestStreamingTimeoutHeadersOnly(t *testing.T) {
+	h := StreamingTimeoutMiddleware(time.Second, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Probe", "1")
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Probe") != "1" {
+		t.Fatalf("got %d %v, want 200 with the handler's headers", rec.Code, rec.Header())
+	}
+}
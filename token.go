@@ -0,0 +1,88 @@
+package apikit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gosqueak/jwt"
+)
+
+// the token's sub claim
+func tokenSubject(token jwt.Jwt) string {
+	return token.Body.Subject
+}
+
+// the token's aud claim
+func tokenAudience(token jwt.Jwt) string {
+	return token.Body.Audience
+}
+
+// the token's exp claim. a malformed claim reads as the zero time, i.e. already expired
+func tokenExpiry(token jwt.Jwt) time.Time {
+	exp, err := strconv.ParseInt(token.Body.Expiration, 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(exp, 0)
+}
+
+// the time left until the token expires, zero if it already has.
+// useful for e.g. a Cache-Control max-age that shouldn't outlive the token
+func TokenRemaining(token jwt.Jwt) time.Duration {
+	if remaining := time.Until(tokenExpiry(token)); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+var ErrNoToken = errors.New("no token in request")
+
+// pulls the raw token string out of a request. returns ErrNoToken when it isn't present
+type TokenExtractor func(r *http.Request) (string, error)
+
+func CookieTokenExtractor(name string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		cookie, err := GetHttpCookie(r, name)
+		if err != nil || cookie.Value == "" {
+			return "", ErrNoToken
+		}
+		return cookie.Value, nil
+	}
+}
+
+// reads an "Authorization: Bearer <token>" header
+func BearerTokenExtractor() TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			return "", ErrNoToken
+		}
+		return token, nil
+	}
+}
+
+func QueryTokenExtractor(param string) TokenExtractor {
+	return func(r *http.Request) (string, error) {
+		token := r.URL.Query().Get(param)
+		if token == "" {
+			return "", ErrNoToken
+		}
+		return token, nil
+	}
+}
+
+// the token string as sent, before parsing. e.g. for hashing into audit logs
+func RawTokenFromRequest(r *http.Request, extractor TokenExtractor) (string, error) {
+	return extractor(r)
+}
+
+func TokenFromRequest(r *http.Request, extractor TokenExtractor) (jwt.Jwt, error) {
+	raw, err := RawTokenFromRequest(r, extractor)
+	if err != nil {
+		return jwt.Jwt{}, err
+	}
+	return jwt.FromString(raw)
+}
@@ -0,0 +1,99 @@
+package apikit
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gosqueak/apikit/apitest"
+	"github.com/gosqueak/jwt"
+)
+
+var (
+	testKitOnce sync.Once
+	testKit     *apitest.TokenKit
+)
+
+// a TokenKit for the "test-aud" audience, shared because key generation is slow
+func tokenKit() *apitest.TokenKit {
+	testKitOnce.Do(func() { testKit = apitest.NewTokenKit("test-aud") })
+	return testKit
+}
+
+// claims only, TokenRemaining doesn't look at the signature
+func tokenExpiringIn(d time.Duration) jwt.Jwt {
+	return jwt.Jwt{Body: jwt.Body{Subject: "ann", Expiration: strconv.FormatInt(time.Now().Add(d).Unix(), 10)}}
+}
+
+func TestTokenRemaining(t *testing.T) {
+	if got := TokenRemaining(tokenExpiringIn(time.Hour)); got <= 59*time.Minute || got > time.Hour {
+		t.Fatalf("TokenRemaining = %v, want about an hour", got)
+	}
+
+	if got := TokenRemaining(tokenExpiringIn(-time.Minute)); got != 0 {
+		t.Fatalf("TokenRemaining of expired token = %v, want 0", got)
+	}
+
+	for _, exp := range []string{"", "not-a-number", "1e9"} {
+		token := jwt.Jwt{Body: jwt.Body{Expiration: exp}}
+		if got := TokenRemaining(token); got != 0 {
+			t.Fatalf("TokenRemaining with exp %q = %v, want 0", exp, got)
+		}
+	}
+}
+
+func TestTokenExtractors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?access_token=from-query", nil)
+	r.Header.Set("Authorization", "Bearer from-header")
+	r.AddCookie(&http.Cookie{Name: "jwt", Value: "from-cookie"})
+
+	for name, tc := range map[string]struct {
+		extractor TokenExtractor
+		want      string
+	}{
+		"cookie": {CookieTokenExtractor("jwt"), "from-cookie"},
+		"bearer": {BearerTokenExtractor(), "from-header"},
+		"query":  {QueryTokenExtractor("access_token"), "from-query"},
+	} {
+		got, err := RawTokenFromRequest(r, tc.extractor)
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q, %v; want %q", name, got, err, tc.want)
+		}
+	}
+
+	empty := httptest.NewRequest(http.MethodGet, "/", nil)
+	empty.Header.Set("Authorization", "Basic abc")
+	for name, extractor := range map[string]TokenExtractor{
+		"cookie": CookieTokenExtractor("jwt"),
+		"bearer": BearerTokenExtractor(),
+		"query":  QueryTokenExtractor("access_token"),
+	} {
+		if _, err := RawTokenFromRequest(empty, extractor); err != ErrNoToken {
+			t.Errorf("%s: err = %v, want ErrNoToken", name, err)
+		}
+	}
+}
+
+func TestTokenFromRequest(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := jwt.NewIssuer(key, "test")
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+issuer.StringifyJwt(issuer.MintToken("ann", "test-aud", time.Hour)))
+
+	got, err := TokenFromRequest(r, BearerTokenExtractor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokenSubject(got) != "ann" {
+		t.Fatalf("subject = %q, want ann", tokenSubject(got))
+	}
+}
@@ -0,0 +1,54 @@
+package apikit
+
+import (
+	"context"
+	"net/http"
+)
+
+// the subset of a tracing API used by TraceMiddleware. small enough that an OpenTelemetry
+// trace.Tracer and propagator can be adapted without apikit depending on a tracing library
+type Tracer interface {
+	// returns ctx carrying the remote span context found in carrier (e.g. a traceparent
+	// header), or ctx unchanged if there is none
+	Extract(ctx context.Context, carrier http.Header) context.Context
+	// starts a span as a child of any span in ctx, returning a ctx carrying the new span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type Span interface {
+	SetAttribute(key string, value any)
+	// records the HTTP status of the response, adapters decide what counts as an error
+	SetStatus(code int)
+	End()
+}
+
+// Middleware for wrapping each request in a server span, continuing any trace propagated by
+// the caller. spans are named "<method> <route>" using routeName, which should return the
+// route template (e.g. "/users/{id}") rather than the raw path to keep span names low
+// cardinality. a nil routeName, or one returning "", names spans by method only.
+// the span travels in the request context so downstream clients can propagate it
+func TraceMiddleware(tracer Tracer, routeName func(*http.Request) string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.Method
+		if routeName != nil {
+			if route := routeName(r); route != "" {
+				name += " " + route
+			}
+		}
+
+		ctx := tracer.Extract(r.Context(), r.Header)
+		ctx, span := tracer.Start(ctx, name)
+		defer span.End()
+
+		span.SetAttribute("http.method", r.Method)
+		span.SetAttribute("http.target", r.URL.RequestURI())
+		span.SetAttribute("http.host", r.Host)
+		span.SetAttribute("http.user_agent", r.UserAgent())
+
+		lrw := newLoggingResponseWriter(w)
+		next(lrw, r.WithContext(ctx))
+
+		span.SetAttribute("http.status_code", lrw.statusCode)
+		span.SetStatus(lrw.statusCode)
+	}
+}
@@ -0,0 +1,84 @@
+package apikit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type remoteParentKey struct{}
+
+// records the spans it starts. Extract stores any traceparent in the context so tests can
+// check it became the span's parent
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+type recordedSpan struct {
+	name   string
+	parent string
+	attrs  map[string]any
+	status int
+	ended  bool
+}
+
+func (rt *recordingTracer) Extract(ctx context.Context, carrier http.Header) context.Context {
+	if tp := carrier.Get("traceparent"); tp != "" {
+		return context.WithValue(ctx, remoteParentKey{}, tp)
+	}
+	return ctx
+}
+
+func (rt *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(remoteParentKey{}).(string)
+	span := &recordedSpan{name: name, parent: parent, attrs: make(map[string]any)}
+	rt.spans = append(rt.spans, span)
+	return ctx, span
+}
+
+func (s *recordedSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *recordedSpan) SetStatus(code int)                 { s.status = code }
+func (s *recordedSpan) End()                               { s.ended = true }
+
+func TestTraceMiddleware(t *testing.T) {
+	tracer := &recordingTracer{}
+	route := func(r *http.Request) string { return "/users/{id}" }
+	h := TraceMiddleware(tracer, route, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/users/42?x=1", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h(httptest.NewRecorder(), r)
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("%d spans started, want 1", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+
+	if span.name != "GET /users/{id}" {
+		t.Errorf("name = %q, want the route template", span.name)
+	}
+	if span.parent == "" {
+		t.Error("incoming traceparent was not extracted")
+	}
+	if span.attrs["http.target"] != "/users/42?x=1" {
+		t.Errorf("http.target = %v", span.attrs["http.target"])
+	}
+	if span.status != http.StatusTeapot || span.attrs["http.status_code"] != http.StatusTeapot {
+		t.Errorf("status = %d, http.status_code = %v; want 418", span.status, span.attrs["http.status_code"])
+	}
+	if !span.ended {
+		t.Error("span not ended")
+	}
+}
+
+func TestTraceMiddlewareMethodOnlyName(t *testing.T) {
+	tracer := &recordingTracer{}
+	TraceMiddleware(tracer, nil, okHandler)(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users/42", nil))
+
+	if got := tracer.spans[0].name; got != "POST" {
+		t.Fatalf("name = %q, want POST", got)
+	}
+}
@@ -0,0 +1,90 @@
+package apikit
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+)
+
+var (
+	ErrNoUpload           = errors.New("no file uploaded")
+	ErrUploadTooLarge     = errors.New("upload too large")
+	ErrUnsupportedUpload  = errors.New("unsupported upload type")
+	ErrMalformedMultipart = errors.New("malformed multipart form")
+)
+
+// parses a multipart upload of at most maxBytes and returns the first file in it, provided its
+// sniffed content type is one of allowedTypes. errors map to 400 (ErrNoUpload, ErrMalformedMultipart),
+// 413 (ErrUploadTooLarge) and 415 (ErrUnsupportedUpload). the caller must close the returned file
+func ParseUpload(r *http.Request, maxBytes int64, allowedTypes []string) (*multipart.FileHeader, io.ReadCloser, error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, maxBytes)
+
+	if err := r.ParseMultipartForm(maxBytes); err != nil {
+		if r.MultipartForm != nil {
+			r.MultipartForm.RemoveAll()
+		}
+
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, nil, ErrUploadTooLarge
+		}
+		return nil, nil, ErrMalformedMultipart
+	}
+
+	fh := firstFile(r.MultipartForm)
+	if fh == nil {
+		r.MultipartForm.RemoveAll()
+		return nil, nil, ErrNoUpload
+	}
+
+	file, err := fh.Open()
+	if err != nil {
+		r.MultipartForm.RemoveAll()
+		return nil, nil, err
+	}
+
+	fail := func(err error) (*multipart.FileHeader, io.ReadCloser, error) {
+		file.Close()
+		r.MultipartForm.RemoveAll()
+		return nil, nil, err
+	}
+
+	// DetectContentType considers at most the first 512 bytes
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return fail(err)
+	}
+
+	// drop parameters such as "; charset=utf-8" before comparing
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(sniff[:n]))
+	if !contains(allowedTypes, sniffed) {
+		return fail(ErrUnsupportedUpload)
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fail(err)
+	}
+
+	return fh, file, nil
+}
+
+func firstFile(form *multipart.Form) *multipart.FileHeader {
+	for _, fhs := range form.File {
+		if len(fhs) > 0 {
+			return fhs[0]
+		}
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package apikit
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// builds a multipart upload request with a single file field
+func newUploadRequest(t *testing.T, content []byte) *http.Request {
+	t.Helper()
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("file", "upload.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(content)
+	mw.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestParseUpload(t *testing.T) {
+	fh, file, err := ParseUpload(newUploadRequest(t, pngHeader), 1<<20, []string{"image/png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if fh.Filename != "upload.bin" {
+		t.Fatalf("Filename = %q", fh.Filename)
+	}
+	if b, _ := io.ReadAll(file); !bytes.Equal(b, pngHeader) {
+		t.Fatal("file not rewound after sniffing")
+	}
+}
+
+func TestParseUploadErrors(t *testing.T) {
+	if _, _, err := ParseUpload(newUploadRequest(t, []byte("plain text")), 1<<20, []string{"image/png"}); err != ErrUnsupportedUpload {
+		t.Errorf("text as png: err = %v, want ErrUnsupportedUpload", err)
+	}
+
+	if _, _, err := ParseUpload(newUploadRequest(t, bytes.Repeat([]byte("x"), 4096)), 512, []string{"text/plain"}); err != ErrUploadTooLarge {
+		t.Errorf("oversized: err = %v, want ErrUploadTooLarge", err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("junk")))
+	r.Header.Set("Content-Type", "multipart/form-data; boundary=x")
+	if _, _, err := ParseUpload(r, 1<<20, []string{"image/png"}); err != ErrMalformedMultipart {
+		t.Errorf("malformed: err = %v, want ErrMalformedMultipart", err)
+	}
+}
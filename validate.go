@@ -0,0 +1,130 @@
+package apikit
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]func([]byte) error{}
+)
+
+// registers a body validator under name for use with ApplyValidator.
+// registering the same name again replaces the validator
+func RegisterValidator(name string, fn func([]byte) error) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+
+	validators[name] = fn
+}
+
+// Middleware for validating the request body with the validator registered under name.
+// the validator is looked up per request, so it may be registered after wiring.
+// bodies over maxBytes are rejected with 413
+func ApplyValidator(name string, maxBytes int64, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		validatorsMu.RLock()
+		validate, ok := validators[name]
+		validatorsMu.RUnlock()
+
+		if !ok {
+			log.Printf("ERROR: no validator registered as %q\n", name)
+			Error(w, "", http.StatusInternalServerError)
+			return
+		}
+
+		body, err := readBody(r, maxBytes)
+		if err != nil {
+			writeReadBodyError(w, err)
+			return
+		}
+
+		if err := validate(body); err != nil {
+			Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// implemented by request models that can check themselves after decoding
+type Validator interface {
+	Validate() error
+}
+
+// a validation failure for a single field
+type ValidationError struct {
+	Field string
+	Msg   string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+// fails when value is longer than max characters. for use in Validate methods
+func MaxLen(field, value string, max int) error {
+	if utf8.RuneCountInString(value) > max {
+		return &ValidationError{field, fmt.Sprintf("must be at most %v characters", max)}
+	}
+	return nil
+}
+
+// decodes the JSON body into dst and, if dst is a Validator, validates it. on failure a
+// 400 (malformed JSON) or 422 (failed validation) is written and false returned,
+// so handlers can simply return
+func DecodeAndValidate(w http.ResponseWriter, r *http.Request, dst any) bool {
+	if err := DecodeJSON(r, dst); err != nil {
+		Error(w, "invalid JSON body", http.StatusBadRequest)
+		return false
+	}
+
+	if v, ok := dst.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			UnprocessableEntity(w, err.Error())
+			return false
+		}
+	}
+	return true
+}
+
+// pragmatic email check: a bare addr-spec with a dotted domain, no display name
+func ValidateEmail(s string) error {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || addr.Address != s {
+		return errors.New("invalid email address")
+	}
+
+	_, domain, _ := strings.Cut(addr.Address, "@")
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return errors.New("invalid email domain")
+	}
+	return nil
+}
+
+// requires an absolute URL with a host, whose scheme is one of allowedSchemes
+// (http and https when none are given)
+func ValidateURL(s string, allowedSchemes ...string) error {
+	if len(allowedSchemes) == 0 {
+		allowedSchemes = []string{"http", "https"}
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return errors.New("invalid URL")
+	}
+
+	if !contains(allowedSchemes, strings.ToLower(u.Scheme)) {
+		return fmt.Errorf("URL scheme %q not allowed, must be one of: %v", u.Scheme, strings.Join(allowedSchemes, ", "))
+	}
+	return nil
+}
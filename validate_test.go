@@ -0,0 +1,144 @@
+package apikit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestApplyValidator(t *testing.T) {
+	RegisterValidator("test.nonempty", func(b []byte) error {
+		if len(bytes.TrimSpace(b)) == 0 {
+			return errors.New("empty body")
+		}
+		return nil
+	})
+
+	var seen string
+	h := ApplyValidator("test.nonempty", 16, func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+	})
+
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{"hello", http.StatusOK},
+		{"   ", http.StatusBadRequest},
+		{strings.Repeat("x", 17), http.StatusRequestEntityTooLarge},
+	} {
+		seen = ""
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)))
+
+		if rec.Code != tc.want {
+			t.Errorf("body %q: status = %d, want %d", tc.body, rec.Code, tc.want)
+		}
+		if tc.want == http.StatusOK && seen != tc.body {
+			t.Errorf("handler read %q, want %q", seen, tc.body)
+		}
+	}
+}
+
+func TestApplyValidatorUnregistered(t *testing.T) {
+	captureLog(t)
+
+	rec := httptest.NewRecorder()
+	ApplyValidator("test.missing", 0, okHandler)(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x")))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+}
+
+type signup struct {
+	Name string `json:"name"`
+	Bio  string `json:"bio"`
+}
+
+func (s *signup) Validate() error {
+	if err := MaxLen("name", s.Name, 5); err != nil {
+		return err
+	}
+	return MaxLen("bio", s.Bio, 10)
+}
+
+func TestMaxLenCountsCharacters(t *testing.T) {
+	if err := MaxLen("name", "héllo", 5); err != nil {
+		t.Fatalf("5 characters rejected: %v", err)
+	}
+
+	err := MaxLen("name", "héllo!", 5)
+	var ve *ValidationError
+	if !errors.As(err, &ve) || ve.Field != "name" {
+		t.Fatalf("err = %v, want a ValidationError for name", err)
+	}
+}
+
+func TestDecodeAndValidate(t *testing.T) {
+	for _, tc := range []struct {
+		body string
+		want int
+	}{
+		{`{"name":"ann","bio":"hi"}`, http.StatusOK},
+		{`{"name":"annabelle"}`, http.StatusUnprocessableEntity},
+		{`{"name":`, http.StatusBadRequest},
+	} {
+		rec := httptest.NewRecorder()
+		var dst signup
+		if DecodeAndValidate(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body)), &dst) {
+			rec.WriteHeader(http.StatusOK)
+		}
+		if rec.Code != tc.want {
+			t.Errorf("%s: status = %d, want %d", tc.body, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestValidateEmail(t *testing.T) {
+	for _, s := range []string{"user@example.com", "first.last+tag@mail.example.co.uk"} {
+		if err := ValidateEmail(s); err != nil {
+			t.Errorf("ValidateEmail(%q) = %v, want nil", s, err)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"user",
+		"user@localhost",
+		"user@.example.com",
+		"user@example.com.",
+		"Name <user@example.com>",
+		" user@example.com",
+	} {
+		if err := ValidateEmail(s); err == nil {
+			t.Errorf("ValidateEmail(%q) = nil, want an error", s)
+		}
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	for _, s := range []string{"https://example.com", "HTTP://example.com/path?q=1"} {
+		if err := ValidateURL(s); err != nil {
+			t.Errorf("ValidateURL(%q) = %v, want nil", s, err)
+		}
+	}
+
+	for _, s := range []string{"", "example.com", "/relative/path", "ftp://example.com", "javascript:alert(1)"} {
+		if err := ValidateURL(s); err == nil {
+			t.Errorf("ValidateURL(%q) = nil, want an error", s)
+		}
+	}
+
+	if err := ValidateURL("ftp://example.com", "ftp"); err != nil {
+		t.Fatalf("ftp with ftp allowed = %v, want nil", err)
+	}
+	err := ValidateURL("https://example.com", "ftp")
+	if err == nil || !strings.Contains(err.Error(), "ftp") {
+		t.Fatalf("https with only ftp allowed = %v, want an error listing ftp", err)
+	}
+}